@@ -0,0 +1,138 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileName is the name of the file a Store keeps its positions in.
+const FileName = "checkpoints.json"
+
+// A Position says how far into a file an input has got. Offset counts the
+// bytes up to the end of the last complete line read. Inode identifies the
+// file, so that a different file at the same path can be told apart.
+type Position struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// A Store keeps the positions of files being read, keyed by their paths. The
+// positions get written to disk only when saved.
+type Store struct {
+	saving    sync.Mutex
+	lock      sync.Mutex
+	path      string
+	positions map[string]Position
+	dirty     bool
+}
+
+type storeFile struct {
+	Files map[string]Position `json:"files"`
+}
+
+// Open loads the positions saved in dir. The directory gets created when
+// missing.
+func Open(dir string) (*Store, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &Store{
+		path:      filepath.Join(dir, FileName),
+		positions: make(map[string]Position),
+	}
+	content, err := ioutil.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved storeFile
+	err = json.Unmarshal(content, &saved)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file %s: %s", store.path, err)
+	}
+	for path, pos := range saved.Files {
+		store.positions[path] = pos
+	}
+	return store, nil
+}
+
+func (store *Store) Get(path string) (Position, bool) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	pos, ok := store.positions[path]
+	return pos, ok
+}
+
+func (store *Store) Set(path string, pos Position) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.positions[path] == pos {
+		return
+	}
+	store.positions[path] = pos
+	store.dirty = true
+}
+
+// Save writes the positions to disk, unless none changed since the last save.
+// The old file gets replaced atomically, so a crash mid-save leaves it intact.
+func (store *Store) Save() error {
+	store.saving.Lock()
+	defer store.saving.Unlock()
+
+	store.lock.Lock()
+	if !store.dirty {
+		store.lock.Unlock()
+		return nil
+	}
+	saved := storeFile{Files: make(map[string]Position, len(store.positions))}
+	for path, pos := range store.positions {
+		saved.Files[path] = pos
+	}
+	store.dirty = false
+	store.lock.Unlock()
+
+	content, err := json.MarshalIndent(saved, "", "\t")
+	if err == nil {
+		err = store.write(content)
+	}
+	if err != nil {
+		store.lock.Lock()
+		store.dirty = true
+		store.lock.Unlock()
+	}
+	return err
+}
+
+func (store *Store) write(content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(store.path), FileName+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), store.path)
+}
@@ -0,0 +1,77 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package checkpoint_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/checkpoint"
+)
+
+func TestStoreKeepsSavedPositionsAcrossRestarts(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := checkpoint.Open(dir)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	want := checkpoint.Position{Inode: 7, Offset: 42}
+	store.Set("/var/log/app.log", want)
+
+	// when
+	err = store.Save()
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	reopened, err := checkpoint.Open(dir)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// then
+	got, ok := reopened.Get("/var/log/app.log")
+	assert.That(ok, t.Fatalf, "no position saved")
+	assert.That(got == want, t.Errorf, "got position %#v, want %#v", got, want)
+}
+
+func TestStoreCreatesMissingDirectory(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	stateDir := filepath.Join(dir, "state")
+
+	// when
+	store, err := checkpoint.Open(stateDir)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	_, ok := store.Get("/var/log/app.log")
+	assert.That(!ok, t.Errorf, "got a position from an empty store")
+	_, err = os.Stat(stateDir)
+	assert.That(err == nil, t.Errorf, "state directory was not created: %s", err)
+}
+
+func TestStoreRejectsCorruptFile(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	err := ioutil.WriteFile(filepath.Join(dir, checkpoint.FileName), []byte("{"), 0644)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	store, err := checkpoint.Open(dir)
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(store == nil, t.Errorf, "got store %#v, want %#v", store, nil)
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "munch-checkpoint")
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	return dir
+}
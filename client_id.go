@@ -29,3 +29,6 @@ func (g *ClientIDGenerator) NextID() ClientID {
 	g.lock.Unlock()
 	return out
 }
+
+// Less orders client IDs by when they were generated.
+func (id ClientID) Less(other ClientID) bool { return id.id < other.id }
@@ -0,0 +1,49 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/szabba/munch/checkpoint"
+)
+
+// A CheckpointService saves checkpoints periodically.
+type CheckpointService struct {
+	once     sync.Once
+	store    *checkpoint.Store
+	interval time.Duration
+	stopped  chan struct{}
+}
+
+func NewCheckpointService(store *checkpoint.Store, interval time.Duration) *CheckpointService {
+	return &CheckpointService{
+		store:    store,
+		interval: interval,
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (s *CheckpointService) Run() error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopped:
+			return nil
+		case <-ticker.C:
+		}
+		err := s.store.Save()
+		if err != nil {
+			log.Printf("cannot save checkpoints: %s", err)
+		}
+	}
+}
+
+func (s *CheckpointService) Stop() {
+	s.once.Do(func() { close(s.stopped) })
+}
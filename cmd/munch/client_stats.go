@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/szabba/munch/notification"
+)
+
+type StatsService interface {
+	Stats() []notification.ClientStats
+}
+
+// A ClientStatsHandler lists the subscribed clients as JSON, with how many
+// messages each has waiting and how many it had dropped.
+type ClientStatsHandler struct {
+	svc StatsService
+}
+
+type clientStats struct {
+	ID      string `json:"id"`
+	Queued  int    `json:"queued"`
+	Dropped uint64 `json:"dropped"`
+}
+
+func (h ClientStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := h.svc.Stats()
+	out := make([]clientStats, len(stats))
+	for i, s := range stats {
+		out[i] = clientStats{ID: s.ID.String(), Queued: s.Queued, Dropped: s.Dropped}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(out)
+	if err != nil {
+		log.Printf("cannot write client stats: %s", err)
+	}
+}
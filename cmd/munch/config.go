@@ -0,0 +1,332 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/handlers"
+	"github.com/szabba/munch/inputs"
+	"github.com/szabba/munch/notification"
+	"github.com/szabba/munch/sources"
+	"github.com/szabba/munch/store"
+	"github.com/szabba/munch/supervisor"
+)
+
+const AnyOrigin = "*"
+
+// A Config describes how to run munch. When StateDir is set, how far each
+// file has been read gets saved there every CheckpointInterval, so that a
+// restart resumes where the last run stopped.
+type Config struct {
+	Listen             string               `json:"listen"`
+	Origins            []string             `json:"origins"`
+	Websocket          WebsocketConfig      `json:"websocket"`
+	Watch              inputs.WatchOptions  `json:"watch"`
+	StateDir           string               `json:"stateDir"`
+	CheckpointInterval munch.Duration       `json:"checkpointInterval"`
+	History            HistoryConfig        `json:"history"`
+	Queue              QueueConfig          `json:"queue"`
+	SSE                SSEConfig            `json:"sse"`
+	Search             SearchConfig         `json:"search"`
+	Store              StoreConfig          `json:"store"`
+	Restart            RestartConfig        `json:"restart"`
+	Control            ControlConfig        `json:"control"`
+	Sources            []sources.Definition `json:"sources"`
+}
+
+// An SSEConfig says how many recent events to keep for server-sent event
+// clients catching up after reconnecting, how many can wait to be written to
+// each, and how often to send keep-alives to idle ones.
+type SSEConfig struct {
+	Backlog   int            `json:"backlog"`
+	Buffer    int            `json:"buffer"`
+	KeepAlive munch.Duration `json:"keepAlive"`
+}
+
+// A SearchConfig says how many of the most recent events can be found through
+// the search endpoint. With a zero window, nothing can.
+type SearchConfig struct {
+	Window int `json:"window"`
+}
+
+// A StoreConfig says where to keep all events on disk. Without a Dir, events
+// are not stored. Every Interval the stored events get flushed to disk and the
+// ones past MaxSize or MaxAge deleted. Zero limits are no limits.
+type StoreConfig struct {
+	Dir         string         `json:"dir"`
+	SegmentSize int64          `json:"segmentSize"`
+	MaxSize     int64          `json:"maxSize"`
+	MaxAge      munch.Duration `json:"maxAge"`
+	Interval    munch.Duration `json:"interval"`
+}
+
+// A RestartConfig says how long to wait before restarting a failed source. The
+// delay doubles with each failure in a row, up to MaxDelay.
+type RestartConfig struct {
+	InitialDelay munch.Duration `json:"initialDelay"`
+	MaxDelay     munch.Duration `json:"maxDelay"`
+}
+
+// A ControlConfig says whether websocket clients can add, remove, pause and
+// resume sources. It is off by default, as a client adding an exec source can
+// run any command munch could.
+type ControlConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// A QueueConfig says how many messages can wait to be sent to each client,
+// and what to do about clients too slow to keep up: "drop-oldest",
+// "drop-newest" or "disconnect".
+type QueueConfig struct {
+	Size     int    `json:"size"`
+	Overflow string `json:"overflow"`
+}
+
+// A HistoryConfig says how many recent events of each source to replay to new
+// clients, and how old they can get. A zero age is no limit.
+type HistoryConfig struct {
+	Events int            `json:"events"`
+	Age    munch.Duration `json:"age"`
+}
+
+type WebsocketConfig struct {
+	ReadBufferSize  int            `json:"readBufferSize"`
+	WriteBufferSize int            `json:"writeBufferSize"`
+	PingInterval    munch.Duration `json:"pingInterval"`
+	IdleTimeout     munch.Duration `json:"idleTimeout"`
+	WriteTimeout    munch.Duration `json:"writeTimeout"`
+	MaxMessageSize  int64          `json:"maxMessageSize"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Listen:  ":8080",
+		Origins: []string{AnyOrigin},
+		Websocket: WebsocketConfig{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			PingInterval:    munch.Duration(handlers.DefaultSocketOptions().PingInterval),
+			IdleTimeout:     munch.Duration(handlers.DefaultSocketOptions().IdleTimeout),
+			WriteTimeout:    munch.Duration(handlers.DefaultSocketOptions().WriteTimeout),
+			MaxMessageSize:  handlers.DefaultSocketOptions().MaxMessageSize,
+		},
+		Watch:              inputs.DefaultWatchOptions(),
+		CheckpointInterval: munch.Duration(time.Second),
+		History:            HistoryConfig{Events: 100},
+		SSE: SSEConfig{
+			Backlog:   handlers.DefaultSSEOptions().Backlog,
+			Buffer:    handlers.DefaultSSEOptions().Buffer,
+			KeepAlive: munch.Duration(handlers.DefaultSSEOptions().KeepAlive),
+		},
+		Search: SearchConfig{Window: 10000},
+		Store: StoreConfig{
+			SegmentSize: store.DefaultOptions().SegmentSize,
+			Interval:    munch.Duration(time.Second),
+		},
+		Restart: RestartConfig{
+			InitialDelay: munch.Duration(supervisor.DefaultBackoff().Initial),
+			MaxDelay:     munch.Duration(supervisor.DefaultBackoff().Max),
+		},
+		Queue: QueueConfig{
+			Size:     notification.DefaultQueueOptions().Size,
+			Overflow: notification.DefaultQueueOptions().Overflow,
+		},
+		Sources: defaultDefinitions,
+	}
+}
+
+// LoadConfig reads the configuration file at path. Settings missing from the
+// file keep their default values. An empty path gives the default
+// configuration.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config file %s: %s", path, err)
+	}
+	return cfg, nil
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Listen == "" {
+		return fmt.Errorf("config: listen address is empty")
+	}
+	_, _, err := net.SplitHostPort(cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("config: invalid listen address %q: %s", cfg.Listen, err)
+	}
+
+	for i, origin := range cfg.Origins {
+		if origin == "" {
+			return fmt.Errorf("config: origins[%d] is empty", i)
+		}
+	}
+
+	if cfg.Websocket.ReadBufferSize < 0 {
+		return fmt.Errorf("config: websocket read buffer size is negative: %d", cfg.Websocket.ReadBufferSize)
+	}
+	if cfg.Websocket.WriteBufferSize < 0 {
+		return fmt.Errorf("config: websocket write buffer size is negative: %d", cfg.Websocket.WriteBufferSize)
+	}
+
+	err = cfg.SocketOptions().Validate()
+	if err != nil {
+		return fmt.Errorf("config: websocket %s", err)
+	}
+
+	err = cfg.SSEOptions().Validate()
+	if err != nil {
+		return fmt.Errorf("config: SSE %s", err)
+	}
+
+	err = cfg.Watch.Validate()
+	if err != nil {
+		return fmt.Errorf("config: %s", err)
+	}
+
+	if cfg.CheckpointInterval <= 0 {
+		return fmt.Errorf("config: checkpoint interval must be positive, got %s", time.Duration(cfg.CheckpointInterval))
+	}
+
+	if cfg.History.Events < 0 {
+		return fmt.Errorf("config: history event count is negative: %d", cfg.History.Events)
+	}
+	if cfg.History.Age < 0 {
+		return fmt.Errorf("config: history age is negative: %s", time.Duration(cfg.History.Age))
+	}
+
+	if cfg.Search.Window < 0 {
+		return fmt.Errorf("config: search window is negative: %d", cfg.Search.Window)
+	}
+
+	err = cfg.storeOptions().Validate()
+	if err != nil {
+		return fmt.Errorf("config: store %s", err)
+	}
+	if cfg.Store.Interval <= 0 {
+		return fmt.Errorf("config: store interval must be positive, got %s", time.Duration(cfg.Store.Interval))
+	}
+
+	err = cfg.backoff().Validate()
+	if err != nil {
+		return fmt.Errorf("config: %s", err)
+	}
+
+	err = cfg.notificationOptions().Queue.Validate()
+	if err != nil {
+		return fmt.Errorf("config: %s", err)
+	}
+
+	return validateDefinitions(cfg.Sources)
+}
+
+func (cfg Config) notificationOptions() notification.Options {
+	opts := notification.DefaultOptions()
+	opts.History = notification.HistoryLimit{
+		Events: cfg.History.Events,
+		Age:    time.Duration(cfg.History.Age),
+	}
+	opts.Queue = notification.QueueOptions{
+		Size:     cfg.Queue.Size,
+		Overflow: cfg.Queue.Overflow,
+	}
+	return opts
+}
+
+func (cfg Config) backoff() supervisor.Backoff {
+	return supervisor.Backoff{
+		Initial: time.Duration(cfg.Restart.InitialDelay),
+		Max:     time.Duration(cfg.Restart.MaxDelay),
+	}
+}
+
+func (cfg Config) storeOptions() store.Options {
+	opts := store.DefaultOptions()
+	opts.SegmentSize = cfg.Store.SegmentSize
+	opts.MaxSize = cfg.Store.MaxSize
+	opts.MaxAge = time.Duration(cfg.Store.MaxAge)
+	return opts
+}
+
+func validateDefinitions(defs []sources.Definition) error {
+	if len(defs) == 0 {
+		return fmt.Errorf("config: no sources defined")
+	}
+	seen := make(map[string]bool, len(defs))
+	for i, def := range defs {
+		switch {
+		case def.Name == "":
+			return fmt.Errorf("config: sources[%d] has no name", i)
+		case seen[def.Name]:
+			return fmt.Errorf("config: sources[%d] reuses the name %q", i, def.Name)
+		}
+		err := def.Validate()
+		if err != nil {
+			return fmt.Errorf("config: %s", err)
+		}
+		seen[def.Name] = true
+	}
+	return nil
+}
+
+func (cfg Config) SocketOptions() handlers.SocketOptions {
+	return handlers.SocketOptions{
+		PingInterval:   time.Duration(cfg.Websocket.PingInterval),
+		IdleTimeout:    time.Duration(cfg.Websocket.IdleTimeout),
+		WriteTimeout:   time.Duration(cfg.Websocket.WriteTimeout),
+		MaxMessageSize: cfg.Websocket.MaxMessageSize,
+	}
+}
+
+func (cfg Config) SSEOptions() handlers.SSEOptions {
+	return handlers.SSEOptions{
+		Backlog:   cfg.SSE.Backlog,
+		Buffer:    cfg.SSE.Buffer,
+		KeepAlive: time.Duration(cfg.SSE.KeepAlive),
+	}
+}
+
+func (cfg Config) Upgrader() websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:  cfg.Websocket.ReadBufferSize,
+		WriteBufferSize: cfg.Websocket.WriteBufferSize,
+		CheckOrigin:     cfg.checkOrigin,
+	}
+}
+
+func (cfg Config) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range cfg.Origins {
+		if allowed == AnyOrigin || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/sources"
+)
+
+func TestLoadConfigKeepsDefaultsOfMissingKeys(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "munch.json", `{"listen": ":9090", "queue": {"size": 16}}`)
+
+	// when
+	cfg, err := LoadConfig(path)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	want := DefaultConfig()
+	assert.That(cfg.Listen == ":9090", t.Errorf, "got listen address %q, want %q", cfg.Listen, ":9090")
+	assert.That(cfg.Queue.Size == 16, t.Errorf, "got queue size %d, want %d", cfg.Queue.Size, 16)
+	assert.That(cfg.Queue.Overflow == want.Queue.Overflow, t.Errorf, "got queue overflow %q, want %q", cfg.Queue.Overflow, want.Queue.Overflow)
+	assert.That(cfg.Websocket == want.Websocket, t.Errorf, "got websocket config %#v, want %#v", cfg.Websocket, want.Websocket)
+	assert.That(len(cfg.Sources) == len(want.Sources), t.Errorf, "got %d sources, want %d", len(cfg.Sources), len(want.Sources))
+}
+
+func TestLoadConfigWithoutPathGivesTheDefaults(t *testing.T) {
+	// when
+	cfg, err := LoadConfig("")
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(cfg.Listen == DefaultConfig().Listen, t.Errorf, "got listen address %q, want %q", cfg.Listen, DefaultConfig().Listen)
+	err = cfg.Validate()
+	assert.That(err == nil, t.Errorf, "default config is invalid: %s", err)
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "munch.json", `{"listen": ":9090", "lisen": ":9091"}`)
+
+	// when
+	_, err := LoadConfig(path)
+
+	// then
+	assert.That(err != nil, t.Fatalf, "got no error, wanted one")
+	assert.That(strings.Contains(err.Error(), "lisen"), t.Errorf, "error %q does not name the unknown key %q", err, "lisen")
+}
+
+func TestConfigValidateRejectsInvalidSettings(t *testing.T) {
+	source := func(name string) sources.Definition {
+		return sources.Definition{
+			Name:            name,
+			InputDefinition: json.RawMessage(`{"kind": "stdin"}`),
+			ParserDefition:  json.RawMessage(`{"kind": "lines"}`),
+		}
+	}
+	cases := map[string]struct {
+		change func(cfg *Config)
+		want   string
+	}{
+		"empty listen address":       {func(cfg *Config) { cfg.Listen = "" }, "listen address is empty"},
+		"invalid listen address":     {func(cfg *Config) { cfg.Listen = "localhost" }, "invalid listen address"},
+		"empty origin":               {func(cfg *Config) { cfg.Origins = []string{"http://a", ""} }, "origins[1] is empty"},
+		"negative read buffer":       {func(cfg *Config) { cfg.Websocket.ReadBufferSize = -1 }, "read buffer size is negative"},
+		"negative write buffer":      {func(cfg *Config) { cfg.Websocket.WriteBufferSize = -1 }, "write buffer size is negative"},
+		"zero ping interval":         {func(cfg *Config) { cfg.Websocket.PingInterval = 0 }, "websocket ping interval"},
+		"zero SSE backlog":           {func(cfg *Config) { cfg.SSE.Backlog = 0 }, "SSE backlog"},
+		"unknown watch mode":         {func(cfg *Config) { cfg.Watch.Mode = "guess" }, "watch mode"},
+		"zero checkpoint interval":   {func(cfg *Config) { cfg.CheckpointInterval = 0 }, "checkpoint interval"},
+		"negative history events":    {func(cfg *Config) { cfg.History.Events = -1 }, "history event count"},
+		"negative history age":       {func(cfg *Config) { cfg.History.Age = munch.Duration(-time.Second) }, "history age"},
+		"negative search window":     {func(cfg *Config) { cfg.Search.Window = -1 }, "search window"},
+		"zero store segment size":    {func(cfg *Config) { cfg.Store.SegmentSize = 0 }, "store segment size"},
+		"zero store interval":        {func(cfg *Config) { cfg.Store.Interval = 0 }, "store interval"},
+		"zero restart delay":         {func(cfg *Config) { cfg.Restart.InitialDelay = 0 }, "initial restart delay"},
+		"unknown overflow policy":    {func(cfg *Config) { cfg.Queue.Overflow = "ignore" }, "overflow"},
+		"no sources":                 {func(cfg *Config) { cfg.Sources = nil }, "no sources defined"},
+		"source without a name":      {func(cfg *Config) { cfg.Sources = []sources.Definition{source("")} }, "sources[0] has no name"},
+		"sources sharing a name":     {func(cfg *Config) { cfg.Sources = []sources.Definition{source("app"), source("app")} }, `sources[1] reuses the name "app"`},
+		"source without a parser":    {func(cfg *Config) { cfg.Sources[0].ParserDefition = nil }, "no parser definition"},
+		"source without an input":    {func(cfg *Config) { cfg.Sources[0].InputDefinition = nil }, "no input definition"},
+		"negative store maximum age": {func(cfg *Config) { cfg.Store.MaxAge = munch.Duration(-time.Second) }, "store max age"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			cfg := DefaultConfig()
+			cfg.Sources = []sources.Definition{source("app")}
+			c.change(&cfg)
+
+			// when
+			err := cfg.Validate()
+
+			// then
+			assert.That(err != nil, t.Fatalf, "got no error, wanted one")
+			assert.That(strings.Contains(err.Error(), c.want), t.Errorf, "error %q does not mention %q", err, c.want)
+		})
+	}
+}
+
+func TestConfigChecksOrigins(t *testing.T) {
+	cases := map[string]struct {
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		"no origin header":   {[]string{"http://a.example"}, "", true},
+		"any origin":         {[]string{AnyOrigin}, "http://b.example", true},
+		"listed origin":      {[]string{"http://a.example", "http://b.example"}, "http://b.example", true},
+		"origin in any case": {[]string{"http://a.example"}, "HTTP://A.EXAMPLE", true},
+		"unlisted origin":    {[]string{"http://a.example"}, "http://b.example", false},
+		"no allowed origins": {nil, "http://a.example", false},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			// given
+			cfg := DefaultConfig()
+			cfg.Origins = c.allowed
+			r, _ := http.NewRequest(http.MethodGet, "http://munch.example/ws", nil)
+			if c.origin != "" {
+				r.Header.Set("Origin", c.origin)
+			}
+
+			// when
+			ok := cfg.Upgrader().CheckOrigin(r)
+
+			// then
+			assert.That(ok == c.want, t.Errorf, "got origin %q allowed %t, want %t", c.origin, ok, c.want)
+		})
+	}
+}
+
+func TestApplyFlagsOverridesTheConfigFile(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	srcPath := writeFile(t, dir, "sources.json", `[{"name": "app", "input": {"kind": "stdin"}, "parser": {"kind": "lines"}}]`)
+
+	flags := flag.NewFlagSet("munch", flag.ContinueOnError)
+	defineFlags(flags)
+	err := flags.Parse([]string{
+		"-listen", ":9090",
+		"-origins", "http://a.example,http://b.example",
+		"-sources", srcPath,
+		"-watch", "poll",
+		"-poll-interval", "5s",
+		"-state-dir", "state",
+	})
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	cfg := DefaultConfig()
+	cfg.Store.Dir = "events"
+
+	// when
+	cfg, err = applyFlags(cfg, flags)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(cfg.Listen == ":9090", t.Errorf, "got listen address %q, want %q", cfg.Listen, ":9090")
+	assert.That(len(cfg.Origins) == 2, t.Errorf, "got origins %q, want two", cfg.Origins)
+	assert.That(len(cfg.Sources) == 1 && cfg.Sources[0].Name == "app", t.Errorf, "got sources %#v, want one named %q", cfg.Sources, "app")
+	assert.That(cfg.Watch.Mode == "poll", t.Errorf, "got watch mode %q, want %q", cfg.Watch.Mode, "poll")
+	assert.That(time.Duration(cfg.Watch.PollInterval) == 5*time.Second, t.Errorf, "got poll interval %s, want %s", time.Duration(cfg.Watch.PollInterval), 5*time.Second)
+	assert.That(cfg.StateDir == "state", t.Errorf, "got state dir %q, want %q", cfg.StateDir, "state")
+	assert.That(cfg.Store.Dir == "events", t.Errorf, "got store dir %q, want the one from the file, %q", cfg.Store.Dir, "events")
+}
+
+func TestApplyFlagsReportsTheFirstFlagError(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// The poll interval is a string here, so that it can fail to parse before
+	// the sources fail to load.
+	flags := flag.NewFlagSet("munch", flag.ContinueOnError)
+	flags.String("poll-interval", "", "")
+	flags.String("sources", "", "")
+	err := flags.Parse([]string{"-poll-interval", "soon", "-sources", filepath.Join(dir, "missing.json")})
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	_, err = applyFlags(DefaultConfig(), flags)
+
+	// then
+	assert.That(err != nil, t.Fatalf, "got no error, wanted one")
+	assert.That(strings.Contains(err.Error(), "soon"), t.Errorf, "got error %q, want the one about the poll interval", err)
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "munch-cmd")
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	return path
+}
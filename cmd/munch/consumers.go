@@ -0,0 +1,44 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"log"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/parsers"
+	"github.com/szabba/munch/store"
+)
+
+type BroadcastService interface {
+	Broadcast(msg interface{})
+}
+
+type BroadcastConsumer struct {
+	cast BroadcastService
+}
+
+var _ parsers.EventConsumer = BroadcastConsumer{}
+
+func (cons BroadcastConsumer) On(evt munch.Event) error {
+	cons.cast.Broadcast(evt)
+	return nil
+}
+
+// A StoringConsumer appends events to a store. Events that cannot be stored
+// only get logged, so that a full disk does not stop the sources.
+type StoringConsumer struct {
+	store *store.Store
+}
+
+var _ parsers.EventConsumer = StoringConsumer{}
+
+func (cons StoringConsumer) On(evt munch.Event) error {
+	err := cons.store.Append(evt)
+	if err != nil {
+		log.Printf("cannot store event from source %q: %s", evt.Source, err)
+	}
+	return nil
+}
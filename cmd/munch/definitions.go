@@ -0,0 +1,34 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/szabba/munch/sources"
+)
+
+var defaultDefinitions = []sources.Definition{{
+	Name:            "tail",
+	InputDefinition: json.RawMessage(`{"path": "./log", "start": "beginning", "follow": true}`),
+	ParserDefition:  json.RawMessage(`{"kind": "lines"}`),
+}}
+
+func LoadDefinitions(path string) ([]sources.Definition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var defs []sources.Definition
+	err = json.NewDecoder(f).Decode(&defs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source definitions in %s: %s", path, err)
+	}
+	return defs, nil
+}
@@ -5,65 +5,177 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"net"
 	"net/http"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/szabba/munch/notification"
 
-	"github.com/fstab/grok_exporter/tailer"
-	"github.com/gorilla/websocket"
 	"github.com/oklog/run"
 
 	"github.com/szabba/munch"
+	"github.com/szabba/munch/checkpoint"
+	"github.com/szabba/munch/control"
+	"github.com/szabba/munch/filters"
 	"github.com/szabba/munch/handlers"
+	"github.com/szabba/munch/inputs"
+	"github.com/szabba/munch/parsers"
+	"github.com/szabba/munch/protocol"
+	"github.com/szabba/munch/search"
+	"github.com/szabba/munch/sources"
+	"github.com/szabba/munch/store"
 )
 
 func main() {
-	addr := ":8080"
+	cfgPath := defineFlags(flag.CommandLine)
+	flag.Parse()
 
-	interruptHandler := NewInterruptHandler()
+	cfg, err := LoadConfig(*cfgPath)
+	logErr(err, log.Fatal)
+	cfg, err = applyFlags(cfg, flag.CommandLine)
+	logErr(err, log.Fatal)
+	logErr(cfg.Validate(), log.Fatal)
 
-	tail := NewFSTailer()
-	defer tail.Close()
+	interruptHandler := NewInterruptHandler()
 
-	notifSvc := notification.NewService()
+	notifSvc := notification.NewServiceWith(cfg.notificationOptions())
 	defer notifSvc.Close()
 
-	tailService := NewTailService(tail, notifSvc)
+	var checkpoints *checkpoint.Store
+	if cfg.StateDir != "" {
+		checkpoints, err = checkpoint.Open(cfg.StateDir)
+		logErr(err, log.Fatal)
+	}
+
+	window := search.NewWindow(cfg.Search.Window)
+	consumers := []parsers.EventConsumer{window}
 
-	upgrader := websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-		CheckOrigin:     func(_ *http.Request) bool { return true },
+	var evtStore *store.Store
+	if cfg.Store.Dir != "" {
+		evtStore, err = store.Open(cfg.Store.Dir, cfg.storeOptions())
+		logErr(err, log.Fatal)
+		defer evtStore.Close()
+		consumers = append(consumers, StoringConsumer{evtStore})
 	}
+	consumers = append(consumers, BroadcastConsumer{notifSvc})
+
+	srcFactory := sources.NewFactory(
+		inputs.NewFactory(cfg.Watch, checkpoints),
+		parsers.NewFactory(time.Now, parsers.Tee(consumers...)))
+
+	srcManager := control.NewManager(srcFactory, cfg.backoff(), notifSvc)
+	for _, def := range cfg.Sources {
+		err := srcManager.Add(def)
+		logErr(err, log.Fatal)
+	}
+
+	upgrader := cfg.Upgrader()
 
 	clientIDGen := new(munch.ClientIDGenerator)
 
-	sockHandler := handlers.NewSocket(upgrader, clientIDGen, handlers.Discard(), TagFormatter{}, notifSvc)
+	registry := protocol.NewRegistry()
+
+	msgHandlers := map[reflect.Type]handlers.OnMessager{
+		reflect.TypeOf(filters.Subscribe{}): filters.NewHandler(notifSvc),
+	}
+	if cfg.Control.Enabled {
+		for typ, h := range control.Handlers(srcManager) {
+			msgHandlers[typ] = h
+		}
+	}
+	mux := handlers.NewMux(registry, msgHandlers, notifSvc)
+
+	sockHandler := handlers.NewSocket(upgrader, clientIDGen, mux, registry, notifSvc)
+	sockHandler.SetOptions(cfg.SocketOptions())
+
+	sseHandler := handlers.NewSSE(clientIDGen, registry, notifSvc, cfg.SSEOptions())
+	defer sseHandler.Close()
 
-	l, err := net.Listen("tcp", addr)
+	httpMux := http.NewServeMux()
+	httpMux.Handle("/", sockHandler)
+	httpMux.Handle("/stream", sseHandler)
+	httpMux.Handle("/events", search.NewHandler(window, registry))
+	if evtStore != nil {
+		httpMux.Handle("/archive", store.NewHandler(evtStore, registry))
+	}
+	httpMux.Handle("/clients", ClientStatsHandler{notifSvc})
+	httpMux.Handle("/sources", SourceStatusHandler{srcManager})
+
+	l, err := net.Listen("tcp", cfg.Listen)
 	logErr(err, log.Fatal)
-	log.Printf("listening on %q", addr)
+	log.Printf("listening on %q", cfg.Listen)
 
 	var group run.Group
 
 	group.Add(interruptHandler.Run, func(_ error) { interruptHandler.Stop() })
-	group.Add(tailService.Run, func(_ error) { tailService.Stop() })
+	group.Add(srcManager.Run, func(_ error) { srcManager.Stop() })
+	if checkpoints != nil {
+		cpService := NewCheckpointService(checkpoints, time.Duration(cfg.CheckpointInterval))
+		group.Add(cpService.Run, func(_ error) { cpService.Stop() })
+	}
+	if evtStore != nil {
+		storeService := NewStoreService(evtStore, time.Duration(cfg.Store.Interval))
+		group.Add(storeService.Run, func(_ error) { storeService.Stop() })
+	}
 	group.Add(
-		func() error { return http.Serve(l, sockHandler) },
+		func() error { return http.Serve(l, httpMux) },
 		func(_ error) { l.Close() },
 	)
 
-	logErr(group.Run(), log.Fatal)
+	err = group.Run()
+	if checkpoints != nil {
+		logErr(checkpoints.Save(), log.Print)
+	}
+	logErr(err, log.Fatal)
+}
+
+// defineFlags defines the command line flags on flags. It returns the path to
+// the config file, as the other flags only override what is in it.
+func defineFlags(flags *flag.FlagSet) *string {
+	cfgPath := flags.String("config", "", "path to a JSON config file")
+	flags.String("listen", "", "address to listen on, overrides the config file")
+	flags.String("origins", "", "comma-separated list of allowed websocket origins, overrides the config file")
+	flags.String("sources", "", "path to a JSON file listing source definitions, overrides the config file")
+	flags.String("watch", "", "how to watch followed files by default (events or poll), overrides the config file")
+	flags.Duration("poll-interval", 0, "how often to poll followed files by default, overrides the config file")
+	flags.String("state-dir", "", "directory to save read checkpoints in, overrides the config file")
+	flags.String("store-dir", "", "directory to store events in, overrides the config file")
+	return cfgPath
 }
 
-func NewFSTailer() tailer.Tailer {
-	const (
-		readAll       = true
-		failOnMissing = false
-	)
-	return tailer.RunFseventFileTailer("./log", readAll, failOnMissing, nil)
+// applyFlags overrides cfg with the flags that were set. It reports the first
+// flag it fails on.
+func applyFlags(cfg Config, flags *flag.FlagSet) (Config, error) {
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		value := f.Value.String()
+		switch f.Name {
+		case "listen":
+			cfg.Listen = value
+		case "origins":
+			cfg.Origins = strings.Split(value, ",")
+		case "sources":
+			cfg.Sources, err = LoadDefinitions(value)
+		case "state-dir":
+			cfg.StateDir = value
+		case "store-dir":
+			cfg.Store.Dir = value
+		case "watch":
+			cfg.Watch.Mode = value
+		case "poll-interval":
+			var interval time.Duration
+			interval, err = time.ParseDuration(value)
+			cfg.Watch.PollInterval = munch.Duration(interval)
+		}
+	})
+	return cfg, err
 }
 
 func logErr(err error, logF func(...interface{})) {
@@ -0,0 +1,54 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/szabba/munch/store"
+)
+
+// A StoreService periodically flushes stored events to disk and deletes the
+// ones past the retention limits.
+type StoreService struct {
+	once     sync.Once
+	store    *store.Store
+	interval time.Duration
+	stopped  chan struct{}
+}
+
+func NewStoreService(store *store.Store, interval time.Duration) *StoreService {
+	return &StoreService{
+		store:    store,
+		interval: interval,
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (s *StoreService) Run() error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopped:
+			return nil
+		case <-ticker.C:
+		}
+		err := s.store.Sync()
+		if err != nil {
+			log.Printf("cannot flush stored events: %s", err)
+		}
+		err = s.store.Prune()
+		if err != nil {
+			log.Printf("cannot delete old stored events: %s", err)
+		}
+	}
+}
+
+func (s *StoreService) Stop() {
+	s.once.Do(func() { close(s.stopped) })
+}
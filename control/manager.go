@@ -0,0 +1,207 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package control
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/sources"
+	"github.com/szabba/munch/supervisor"
+)
+
+// A SourceFactory checks source definitions and creates sources out of them.
+type SourceFactory interface {
+	Validate(def sources.Definition) error
+	NewSource(def sources.Definition) (*sources.Source, error)
+}
+
+// A Manager runs a changing set of sources, each under a supervisor. Every
+// change to the set gets broadcast as the status of the source affected.
+type Manager struct {
+	fact    SourceFactory
+	backoff supervisor.Backoff
+	cast    supervisor.BroadcastService
+	running sync.WaitGroup
+	stopped chan struct{}
+
+	lock    sync.Mutex
+	managed map[string]*managed
+}
+
+type managed struct {
+	def    sources.Definition
+	sup    *supervisor.Supervisor
+	paused munch.SourceStatus
+}
+
+func NewManager(fact SourceFactory, backoff supervisor.Backoff, cast supervisor.BroadcastService) *Manager {
+	return &Manager{
+		fact:    fact,
+		backoff: backoff,
+		cast:    cast,
+		stopped: make(chan struct{}),
+		managed: make(map[string]*managed),
+	}
+}
+
+// Add starts a new source. The definition is checked before anything gets
+// started, and its name must not be used by another source.
+func (mgr *Manager) Add(def sources.Definition) error {
+	err := mgr.fact.Validate(def)
+	if err != nil {
+		return err
+	}
+
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+	if mgr.isStopped() {
+		return fmt.Errorf("cannot add source %q: the manager is stopped", def.Name)
+	}
+	if mgr.managed[def.Name] != nil {
+		return fmt.Errorf("there already is a source named %q", def.Name)
+	}
+	m := &managed{def: def}
+	err = mgr.start(m)
+	if err != nil {
+		return err
+	}
+	mgr.managed[def.Name] = m
+	return nil
+}
+
+// Remove stops the source and forgets about it.
+func (mgr *Manager) Remove(name string) error {
+	mgr.lock.Lock()
+	m := mgr.managed[name]
+	if m == nil {
+		mgr.lock.Unlock()
+		return fmt.Errorf("there is no source named %q", name)
+	}
+	delete(mgr.managed, name)
+	mgr.lock.Unlock()
+
+	if m.sup != nil {
+		m.sup.Stop()
+	}
+	mgr.cast.Broadcast(munch.SourceStatus{
+		Source: name,
+		State:  munch.SourceRemoved,
+		Since:  time.Now(),
+	})
+	return nil
+}
+
+// Pause stops the source until it is resumed.
+func (mgr *Manager) Pause(name string) error {
+	mgr.lock.Lock()
+	m := mgr.managed[name]
+	switch {
+	case m == nil:
+		mgr.lock.Unlock()
+		return fmt.Errorf("there is no source named %q", name)
+	case m.sup == nil:
+		mgr.lock.Unlock()
+		return fmt.Errorf("source %q is already paused", name)
+	}
+	sup := m.sup
+	m.sup = nil
+	m.paused = munch.SourceStatus{
+		Source:   name,
+		State:    munch.SourcePaused,
+		Since:    time.Now(),
+		Restarts: sup.Status().Restarts,
+	}
+	status := m.paused
+	mgr.lock.Unlock()
+
+	sup.Stop()
+	mgr.cast.Broadcast(status)
+	return nil
+}
+
+// Resume starts a paused source again, with the definition it was added with.
+func (mgr *Manager) Resume(name string) error {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+	m := mgr.managed[name]
+	switch {
+	case m == nil:
+		return fmt.Errorf("there is no source named %q", name)
+	case m.sup != nil:
+		return fmt.Errorf("source %q is not paused", name)
+	case mgr.isStopped():
+		return fmt.Errorf("cannot resume source %q: the manager is stopped", name)
+	}
+	return mgr.start(m)
+}
+
+func (mgr *Manager) start(m *managed) error {
+	def := m.def
+	newSource := func() (*sources.Source, error) { return mgr.fact.NewSource(def) }
+	sup, err := supervisor.New(def.Name, newSource, mgr.backoff, mgr.cast)
+	if err != nil {
+		return fmt.Errorf("cannot create source %q: %s", def.Name, err)
+	}
+	m.sup = sup
+
+	mgr.running.Add(1)
+	go func() {
+		defer mgr.running.Done()
+		sup.Run()
+	}()
+	mgr.cast.Broadcast(sup.Status())
+	return nil
+}
+
+// Statuses lists the statuses of the sources, ordered by name.
+func (mgr *Manager) Statuses() []munch.SourceStatus {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+	out := make([]munch.SourceStatus, 0, len(mgr.managed))
+	for _, m := range mgr.managed {
+		if m.sup == nil {
+			out = append(out, m.paused)
+		} else {
+			out = append(out, m.sup.Status())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+	return out
+}
+
+// Run waits until the manager is stopped and all of its sources are done.
+func (mgr *Manager) Run() error {
+	<-mgr.stopped
+	mgr.running.Wait()
+	return nil
+}
+
+// Stop stops all the sources. No new ones can be added after that.
+func (mgr *Manager) Stop() {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+	if mgr.isStopped() {
+		return
+	}
+	close(mgr.stopped)
+	for _, m := range mgr.managed {
+		if m.sup != nil {
+			m.sup.Stop()
+		}
+	}
+}
+
+func (mgr *Manager) isStopped() bool {
+	select {
+	case <-mgr.stopped:
+		return true
+	default:
+		return false
+	}
+}
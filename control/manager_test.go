@@ -0,0 +1,262 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package control_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/control"
+	"github.com/szabba/munch/sources"
+	"github.com/szabba/munch/supervisor"
+)
+
+const Timeout = 5 * time.Second
+
+var Backoff = supervisor.Backoff{Initial: 10 * time.Millisecond, Max: 40 * time.Millisecond}
+
+func TestManagerAddsSource(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	fact := NewFakeFactory()
+	mgr := control.NewManager(fact, Backoff, cast)
+	defer stop(mgr)
+
+	// when
+	err := mgr.Add(definition("app"))
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	status := cast.Next(t).(munch.SourceStatus)
+	assert.That(status.Source == "app", t.Errorf, "got status of source %q, want %q", status.Source, "app")
+	assert.That(status.State == munch.SourceRunning, t.Errorf, "got state %q, want %q", status.State, munch.SourceRunning)
+	assert.That(fact.Created() == 1, t.Errorf, "got %d sources created, want %d", fact.Created(), 1)
+}
+
+func TestManagerDoesNotStartInvalidSource(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	fact := NewFakeFactory()
+	fact.invalid = errors.New("no parser")
+	mgr := control.NewManager(fact, Backoff, cast)
+	defer stop(mgr)
+
+	// when
+	err := mgr.Add(definition("app"))
+
+	// then
+	assert.That(err == fact.invalid, t.Errorf, "got error %v, want %q", err, fact.invalid)
+	assert.That(fact.Created() == 0, t.Errorf, "got %d sources created, want %d", fact.Created(), 0)
+	assert.That(len(mgr.Statuses()) == 0, t.Errorf, "got statuses %#v, want none", mgr.Statuses())
+	cast.AssertNothing(t, 2*Backoff.Initial)
+}
+
+func TestManagerRejectsDuplicateName(t *testing.T) {
+	// given
+	mgr := control.NewManager(NewFakeFactory(), Backoff, NewCaptureBroadcast())
+	defer stop(mgr)
+	err := mgr.Add(definition("app"))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	err = mgr.Add(definition("app"))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(len(mgr.Statuses()) == 1, t.Errorf, "got %d statuses, want %d", len(mgr.Statuses()), 1)
+}
+
+func TestManagerRemovesSource(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	mgr := control.NewManager(NewFakeFactory(), Backoff, cast)
+	defer stop(mgr)
+	err := mgr.Add(definition("app"))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	cast.Next(t)
+
+	// when
+	err = mgr.Remove("app")
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	status := cast.Next(t).(munch.SourceStatus)
+	assert.That(status.State == munch.SourceRemoved, t.Errorf, "got state %q, want %q", status.State, munch.SourceRemoved)
+	assert.That(len(mgr.Statuses()) == 0, t.Errorf, "got statuses %#v, want none", mgr.Statuses())
+}
+
+func TestManagerPausesAndResumesSource(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	fact := NewFakeFactory()
+	mgr := control.NewManager(fact, Backoff, cast)
+	defer stop(mgr)
+	err := mgr.Add(definition("app"))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	cast.Next(t)
+
+	// when
+	pauseErr := mgr.Pause("app")
+	paused := cast.Next(t).(munch.SourceStatus)
+	resumeErr := mgr.Resume("app")
+	resumed := cast.Next(t).(munch.SourceStatus)
+
+	// then
+	assert.That(pauseErr == nil, t.Errorf, "unexpected error pausing: %s", pauseErr)
+	assert.That(resumeErr == nil, t.Errorf, "unexpected error resuming: %s", resumeErr)
+	assert.That(paused.State == munch.SourcePaused, t.Errorf, "got state %q, want %q", paused.State, munch.SourcePaused)
+	assert.That(resumed.State == munch.SourceRunning, t.Errorf, "got state %q, want %q", resumed.State, munch.SourceRunning)
+	assert.That(fact.Created() == 2, t.Errorf, "got %d sources created, want %d", fact.Created(), 2)
+}
+
+func TestManagerDoesNotResumeRunningSource(t *testing.T) {
+	// given
+	mgr := control.NewManager(NewFakeFactory(), Backoff, NewCaptureBroadcast())
+	defer stop(mgr)
+	err := mgr.Add(definition("app"))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	err = mgr.Resume("app")
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+}
+
+func TestManagerListsStatusesByName(t *testing.T) {
+	// given
+	mgr := control.NewManager(NewFakeFactory(), Backoff, NewCaptureBroadcast())
+	defer stop(mgr)
+	for _, name := range []string{"web", "app", "db"} {
+		err := mgr.Add(definition(name))
+		assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	}
+	err := mgr.Pause("db")
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	statuses := mgr.Statuses()
+
+	// then
+	want := []string{"app", "db", "web"}
+	assert.That(len(statuses) == len(want), t.Fatalf, "got %d statuses, want %d", len(statuses), len(want))
+	for i, name := range want {
+		assert.That(statuses[i].Source == name, t.Errorf, "got status %d for source %q, want %q", i, statuses[i].Source, name)
+	}
+	assert.That(statuses[1].State == munch.SourcePaused, t.Errorf, "got state %q, want %q", statuses[1].State, munch.SourcePaused)
+}
+
+func TestManagerStopsAllSources(t *testing.T) {
+	// given
+	mgr := control.NewManager(NewFakeFactory(), Backoff, NewCaptureBroadcast())
+	err := mgr.Add(definition("app"))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	done := make(chan error, 1)
+	go func() { done <- mgr.Run() }()
+
+	// when
+	mgr.Stop()
+
+	// then
+	select {
+	case err := <-done:
+		assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	case <-time.After(Timeout):
+		t.Fatalf("manager did not stop")
+	}
+	err = mgr.Add(definition("db"))
+	assert.That(err != nil, t.Errorf, "got no error adding a source after stopping, wanted one")
+}
+
+func stop(mgr *control.Manager) {
+	mgr.Stop()
+	mgr.Run()
+}
+
+func definition(name string) sources.Definition {
+	return sources.Definition{
+		Name:            name,
+		InputDefinition: json.RawMessage(`{}`),
+		ParserDefition:  json.RawMessage(`{}`),
+	}
+}
+
+// A FakeFactory creates sources that block until stopped.
+type FakeFactory struct {
+	invalid error
+
+	lock    sync.Mutex
+	created int
+}
+
+func NewFakeFactory() *FakeFactory {
+	return new(FakeFactory)
+}
+
+func (fact *FakeFactory) Validate(_ sources.Definition) error {
+	return fact.invalid
+}
+
+func (fact *FakeFactory) NewSource(_ sources.Definition) (*sources.Source, error) {
+	fact.lock.Lock()
+	defer fact.lock.Unlock()
+	fact.created++
+	in, _ := io.Pipe()
+	return sources.New(in, DiscardParser{}), nil
+}
+
+func (fact *FakeFactory) Created() int {
+	fact.lock.Lock()
+	defer fact.lock.Unlock()
+	return fact.created
+}
+
+type DiscardParser struct{}
+
+func (DiscardParser) Write(p []byte) (int, error) { return len(p), nil }
+
+func (DiscardParser) Close() error { return nil }
+
+type CaptureBroadcast struct {
+	msgs chan interface{}
+}
+
+func NewCaptureBroadcast() *CaptureBroadcast {
+	return &CaptureBroadcast{msgs: make(chan interface{}, 64)}
+}
+
+func (c *CaptureBroadcast) Broadcast(msg interface{}) {
+	select {
+	case c.msgs <- msg:
+	default:
+	}
+}
+
+func (c *CaptureBroadcast) Next(t *testing.T) interface{} {
+	t.Helper()
+	select {
+	case msg := <-c.msgs:
+		return msg
+	case <-time.After(Timeout):
+		t.Fatalf("nothing was broadcast")
+		return nil
+	}
+}
+
+func (c *CaptureBroadcast) AssertNothing(t *testing.T, wait time.Duration) {
+	t.Helper()
+	select {
+	case msg := <-c.msgs:
+		t.Errorf("unexpected broadcast: %#v", msg)
+	case <-time.After(wait):
+	}
+}
@@ -0,0 +1,69 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/handlers"
+	"github.com/szabba/munch/sources"
+)
+
+// AddSource is sent by clients to start a new source.
+type AddSource sources.Definition
+
+// RemoveSource is sent by clients to stop a source for good. Only the name
+// of the definition is looked at.
+type RemoveSource sources.Definition
+
+// PauseSource is sent by clients to stop a source until it is resumed. Only
+// the name of the definition is looked at.
+type PauseSource sources.Definition
+
+// ResumeSource is sent by clients to start a paused source again. Only the
+// name of the definition is looked at.
+type ResumeSource sources.Definition
+
+// Handlers creates handlers for the control messages, acting on the sources
+// run by the manager.
+func Handlers(mgr *Manager) map[reflect.Type]handlers.OnMessager {
+	return map[reflect.Type]handlers.OnMessager{
+		reflect.TypeOf(AddSource{}): handler{"add", mgr.Add},
+		reflect.TypeOf(RemoveSource{}): handler{"remove", func(def sources.Definition) error {
+			return mgr.Remove(def.Name)
+		}},
+		reflect.TypeOf(PauseSource{}): handler{"pause", func(def sources.Definition) error {
+			return mgr.Pause(def.Name)
+		}},
+		reflect.TypeOf(ResumeSource{}): handler{"resume", func(def sources.Definition) error {
+			return mgr.Resume(def.Name)
+		}},
+	}
+}
+
+type handler struct {
+	verb string
+	do   func(sources.Definition) error
+}
+
+var _ handlers.OnMessager = handler{}
+
+func (h handler) OnMessage(id munch.ClientID, msg json.RawMessage) error {
+	var def sources.Definition
+	err := json.Unmarshal(msg, &def)
+	if err != nil {
+		return fmt.Errorf("invalid request to %s a source: %s", h.verb, err)
+	}
+	err = h.do(def)
+	if err != nil {
+		return fmt.Errorf("cannot %s source %q: %s", h.verb, def.Name, err)
+	}
+	log.Printf("client %s: %s source %q", id, h.verb, def.Name)
+	return nil
+}
@@ -0,0 +1,82 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package control_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/control"
+)
+
+func TestHandlersAddAndRemoveSource(t *testing.T) {
+	// given
+	mgr := control.NewManager(NewFakeFactory(), Backoff, NewCaptureBroadcast())
+	defer stop(mgr)
+	hs := control.Handlers(mgr)
+
+	// when
+	addErr := hs[reflect.TypeOf(control.AddSource{})].OnMessage(munch.ClientIDOf(1), json.RawMessage(`{"name": "app", "input": {}, "parser": {}}`))
+	added := mgr.Statuses()
+	removeErr := hs[reflect.TypeOf(control.RemoveSource{})].OnMessage(munch.ClientIDOf(1), json.RawMessage(`{"name": "app"}`))
+	removed := mgr.Statuses()
+
+	// then
+	assert.That(addErr == nil, t.Errorf, "unexpected error adding: %s", addErr)
+	assert.That(removeErr == nil, t.Errorf, "unexpected error removing: %s", removeErr)
+	assert.That(len(added) == 1, t.Fatalf, "got %d statuses after adding, want %d", len(added), 1)
+	assert.That(added[0].Source == "app", t.Errorf, "got status of source %q, want %q", added[0].Source, "app")
+	assert.That(len(removed) == 0, t.Errorf, "got statuses %#v after removing, want none", removed)
+}
+
+func TestHandlersPauseSource(t *testing.T) {
+	// given
+	mgr := control.NewManager(NewFakeFactory(), Backoff, NewCaptureBroadcast())
+	defer stop(mgr)
+	err := mgr.Add(definition("app"))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	hs := control.Handlers(mgr)
+
+	// when
+	err = hs[reflect.TypeOf(control.PauseSource{})].OnMessage(munch.ClientIDOf(1), json.RawMessage(`{"name": "app"}`))
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	statuses := mgr.Statuses()
+	assert.That(len(statuses) == 1, t.Fatalf, "got %d statuses, want %d", len(statuses), 1)
+	assert.That(statuses[0].State == munch.SourcePaused, t.Errorf, "got state %q, want %q", statuses[0].State, munch.SourcePaused)
+}
+
+func TestHandlersRejectInvalidMessage(t *testing.T) {
+	// given
+	fact := NewFakeFactory()
+	mgr := control.NewManager(fact, Backoff, NewCaptureBroadcast())
+	defer stop(mgr)
+	hs := control.Handlers(mgr)
+
+	// when
+	err := hs[reflect.TypeOf(control.AddSource{})].OnMessage(munch.ClientIDOf(1), json.RawMessage(`["app"]`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(fact.Created() == 0, t.Errorf, "got %d sources created, want %d", fact.Created(), 0)
+}
+
+func TestHandlersReportFailure(t *testing.T) {
+	// given
+	mgr := control.NewManager(NewFakeFactory(), Backoff, NewCaptureBroadcast())
+	defer stop(mgr)
+	hs := control.Handlers(mgr)
+
+	// when
+	err := hs[reflect.TypeOf(control.ResumeSource{})].OnMessage(munch.ClientIDOf(1), json.RawMessage(`{"name": "app"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error resuming a missing source, wanted one")
+}
@@ -0,0 +1,103 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package control_test
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/control"
+	"github.com/szabba/munch/inputs"
+	"github.com/szabba/munch/parsers"
+	"github.com/szabba/munch/sources"
+)
+
+func TestManagerHandsStdinToSourceAddedAfterRemovingItsReader(t *testing.T) {
+	// given
+	r, w := io.Pipe()
+	defer w.Close()
+	events := make(EventChan, 1)
+	mgr := control.NewManager(stdinFactory(r, events), Backoff, NewCaptureBroadcast())
+	defer stop(mgr)
+
+	err := mgr.Add(stdinDefinition("first"))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	err = mgr.Remove("first")
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	err = mgr.Add(stdinDefinition("second"))
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	io.WriteString(w, "hello\n")
+	events.Expect(t, "second", "hello")
+}
+
+func TestManagerResumedStdinSourceGetsTheFollowingLines(t *testing.T) {
+	// given
+	r, w := io.Pipe()
+	defer w.Close()
+	events := make(EventChan, 1)
+	mgr := control.NewManager(stdinFactory(r, events), Backoff, NewCaptureBroadcast())
+	defer stop(mgr)
+
+	err := mgr.Add(stdinDefinition("app"))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	io.WriteString(w, "before\n")
+	events.Expect(t, "app", "before")
+
+	// when
+	err = mgr.Pause("app")
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	err = mgr.Resume("app")
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// then
+	io.WriteString(w, "after\n")
+	events.Expect(t, "app", "after")
+}
+
+func stdinFactory(r io.Reader, events EventChan) *sources.Factory {
+	return sources.NewFactory(inputs.NewStdinFactory(r), parsers.NewFactory(time.Now, events))
+}
+
+func stdinDefinition(name string) sources.Definition {
+	return sources.Definition{
+		Name:            name,
+		InputDefinition: json.RawMessage(`{"kind": "stdin"}`),
+		ParserDefition:  json.RawMessage(`{"kind": "lines"}`),
+	}
+}
+
+type EventChan chan munch.Event
+
+func (evts EventChan) On(evt munch.Event) error {
+	evts <- evt
+	return nil
+}
+
+func (evts EventChan) Expect(t *testing.T, source, msg string) {
+	t.Helper()
+	for {
+		select {
+		case evt := <-evts:
+			if evt.Message == "" {
+				// Stopped sources submit what is left of their input.
+				continue
+			}
+			assert.That(evt.Source == source, t.Errorf, "got event from source %q, want %q", evt.Source, source)
+			assert.That(evt.Message == msg, t.Errorf, "got event message %q, want %q", evt.Message, msg)
+			return
+		case <-time.After(Timeout):
+			t.Fatalf("no event with message %q", msg)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package munch_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+)
+
+func TestDurationUnmarshalsFromString(t *testing.T) {
+	// given
+	var d munch.Duration
+
+	// when
+	err := json.Unmarshal([]byte(`"1m30s"`), &d)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(time.Duration(d) == 90*time.Second, t.Errorf, "got %s, want %s", time.Duration(d), 90*time.Second)
+}
+
+func TestDurationUnmarshalsFromNanoseconds(t *testing.T) {
+	// given
+	var d munch.Duration
+
+	// when
+	err := json.Unmarshal([]byte(`1000`), &d)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(time.Duration(d) == time.Microsecond, t.Errorf, "got %s, want %s", time.Duration(d), time.Microsecond)
+}
+
+func TestDurationRejectsInvalidString(t *testing.T) {
+	// given
+	var d munch.Duration
+
+	// when
+	err := json.Unmarshal([]byte(`"soon"`), &d)
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+}
+
+func TestDurationMarshalsToString(t *testing.T) {
+	// given
+	d := munch.Duration(90 * time.Second)
+
+	// when
+	out, err := json.Marshal(d)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(string(out) == `"1m30s"`, t.Errorf, "got %s, want %s", out, `"1m30s"`)
+}
@@ -7,8 +7,28 @@ package munch
 import "time"
 
 type Event struct {
-	Source  string
-	At      time.Time
-	Message string
-	Fields  map[string]string
+	Source  string            `json:"source"`
+	At      time.Time         `json:"at"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields"`
+}
+
+const (
+	ErrorKindInput  = "input"
+	ErrorKindParser = "parser"
+	ErrorKindExit   = "exit"
+)
+
+// An ErrorEvent reports that a source failed. Kind says what failed: the
+// source's input ("input"), its parser ("parser"), or the command it runs
+// ("exit"). Alive tells whether the source keeps going despite the error: it is
+// true when the source is going to be restarted, and false when it was stopped
+// before it could be. A source reading all of its input is no failure, and only
+// gets reported through its status.
+type ErrorEvent struct {
+	Source  string    `json:"source"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+	At      time.Time `json:"at"`
+	Alive   bool      `json:"alive"`
 }
@@ -0,0 +1,73 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package filters
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/szabba/munch"
+)
+
+// A Spec describes the events a client wants to receive. Empty parts of the
+// spec match all events.
+type Spec struct {
+	Sources  []string          `json:"sources"`
+	Contains string            `json:"contains"`
+	Match    string            `json:"match"`
+	Fields   map[string]string `json:"fields"`
+}
+
+type Filter struct {
+	sources  map[string]bool
+	contains string
+	match    *regexp.Regexp
+	fields   map[string]string
+}
+
+func (spec Spec) Compile() (*Filter, error) {
+	f := &Filter{contains: spec.Contains, fields: spec.Fields}
+	if len(spec.Sources) > 0 {
+		f.sources = make(map[string]bool, len(spec.Sources))
+		for _, src := range spec.Sources {
+			f.sources[src] = true
+		}
+	}
+	if spec.Match != "" {
+		match, err := regexp.Compile(spec.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match pattern: %s", err)
+		}
+		f.match = match
+	}
+	return f, nil
+}
+
+func (f *Filter) Matches(evt munch.Event) bool {
+	if f.sources != nil && !f.sources[evt.Source] {
+		return false
+	}
+	if !strings.Contains(evt.Message, f.contains) {
+		return false
+	}
+	if f.match != nil && !f.match.MatchString(evt.Message) {
+		return false
+	}
+	for k, v := range f.fields {
+		got, ok := evt.Fields[k]
+		if !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Accepts lets through all messages that are not events, and the events that
+// match the filter.
+func (f *Filter) Accepts(msg interface{}) bool {
+	evt, isEvent := msg.(munch.Event)
+	return !isEvent || f.Matches(evt)
+}
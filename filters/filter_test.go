@@ -0,0 +1,73 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package filters_test
+
+import (
+	"testing"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/filters"
+)
+
+var Event = munch.Event{
+	Source:  "app",
+	Message: "GET /index.html 200",
+	Fields:  map[string]string{"status": "200", "method": "GET"},
+}
+
+func TestFilterMatches(t *testing.T) {
+	cases := []struct {
+		name string
+		spec filters.Spec
+		want bool
+	}{
+		{"empty spec", filters.Spec{}, true},
+		{"listed source", filters.Spec{Sources: []string{"db", "app"}}, true},
+		{"unlisted source", filters.Spec{Sources: []string{"db"}}, false},
+		{"contained substring", filters.Spec{Contains: "index"}, true},
+		{"missing substring", filters.Spec{Contains: "POST"}, false},
+		{"matching pattern", filters.Spec{Match: `\s2\d\d$`}, true},
+		{"non-matching pattern", filters.Spec{Match: `\s5\d\d$`}, false},
+		{"equal fields", filters.Spec{Fields: map[string]string{"status": "200", "method": "GET"}}, true},
+		{"different field", filters.Spec{Fields: map[string]string{"status": "500"}}, false},
+		{"missing field", filters.Spec{Fields: map[string]string{"user": ""}}, false},
+		{"all parts matching", filters.Spec{Sources: []string{"app"}, Contains: "GET", Fields: map[string]string{"status": "200"}}, true},
+		{"one part not matching", filters.Spec{Sources: []string{"app"}, Contains: "POST", Fields: map[string]string{"status": "200"}}, false},
+	}
+
+	for _, c := range cases {
+		// given
+		filter, err := c.spec.Compile()
+		assert.That(err == nil, t.Fatalf, "%s: unexpected error: %s", c.name, err)
+
+		// when
+		got := filter.Matches(Event)
+
+		// then
+		assert.That(got == c.want, t.Errorf, "%s: got match %v, want %v", c.name, got, c.want)
+	}
+}
+
+func TestFilterFailsToCompileInvalidPattern(t *testing.T) {
+	// when
+	_, err := filters.Spec{Match: "("}.Compile()
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+}
+
+func TestFilterAcceptsMessagesOtherThanEvents(t *testing.T) {
+	// given
+	filter, err := filters.Spec{Sources: []string{"db"}}.Compile()
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	accepted := filter.Accepts("not an event")
+
+	// then
+	assert.That(accepted, t.Errorf, "filter rejected a message that is not an event")
+}
@@ -0,0 +1,45 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/handlers"
+)
+
+// Subscribe is sent by clients to only get the events matching the spec from
+// then on. It replaces the spec set by an earlier Subscribe.
+type Subscribe Spec
+
+type FilterService interface {
+	SetFilter(munch.ClientID, func(interface{}) bool)
+}
+
+type Handler struct {
+	svc FilterService
+}
+
+var _ handlers.OnMessager = Handler{}
+
+func NewHandler(svc FilterService) Handler {
+	return Handler{svc}
+}
+
+func (h Handler) OnMessage(id munch.ClientID, msg json.RawMessage) error {
+	var sub Subscribe
+	err := json.Unmarshal(msg, &sub)
+	if err != nil {
+		return fmt.Errorf("invalid subscription: %s", err)
+	}
+	filter, err := Spec(sub).Compile()
+	if err != nil {
+		return fmt.Errorf("invalid subscription: %s", err)
+	}
+	h.svc.SetFilter(id, filter.Accepts)
+	return nil
+}
@@ -0,0 +1,58 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package filters_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/filters"
+)
+
+var ClientID = new(munch.ClientIDGenerator).NextID()
+
+func TestHandlerSetsFilterForTheSubscribingClient(t *testing.T) {
+	// given
+	svc := new(CaptureFilterService)
+	h := filters.NewHandler(svc)
+
+	// when
+	err := h.OnMessage(ClientID, json.RawMessage(`{"sources": ["db"]}`))
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(svc.filter != nil, t.Fatalf, "no filter was set")
+	assert.That(svc.id == ClientID, t.Errorf, "got client ID %s, want %s", svc.id, ClientID)
+	assert.That(!svc.filter(Event), t.Errorf, "filter accepted an event from an unlisted source")
+	assert.That(svc.filter(munch.Event{Source: "db"}), t.Errorf, "filter rejected an event from a listed source")
+}
+
+func TestHandlerRejectsInvalidSubscription(t *testing.T) {
+	for _, msg := range []string{`[]`, `{"match": "("}`} {
+		// given
+		svc := new(CaptureFilterService)
+		h := filters.NewHandler(svc)
+
+		// when
+		err := h.OnMessage(ClientID, json.RawMessage(msg))
+
+		// then
+		assert.That(err != nil, t.Errorf, "got no error for invalid subscription %s, wanted one", msg)
+		assert.That(svc.filter == nil, t.Errorf, "filter was set for invalid subscription %s", msg)
+	}
+}
+
+type CaptureFilterService struct {
+	id     munch.ClientID
+	filter func(interface{}) bool
+}
+
+func (svc *CaptureFilterService) SetFilter(id munch.ClientID, filter func(interface{}) bool) {
+	svc.id = id
+	svc.filter = filter
+}
@@ -16,4 +16,4 @@ func Discard() OnMessager {
 
 type discard struct{}
 
-func (_ discard) OnMessage(_ munch.ClientID, _ json.RawMessage) {}
+func (_ discard) OnMessage(_ munch.ClientID, _ json.RawMessage) error { return nil }
@@ -35,13 +35,13 @@ func (m *MockSubscriptionService) EXPECT() *MockSubscriptionServiceMockRecorder
 }
 
 // Subscribe mocks base method
-func (m *MockSubscriptionService) Subscribe(arg0 munch.ClientID, arg1 func(interface{})) {
-	m.ctrl.Call(m, "Subscribe", arg0, arg1)
+func (m *MockSubscriptionService) Subscribe(arg0 munch.ClientID, arg1 func(interface{}), arg2 func(error)) {
+	m.ctrl.Call(m, "Subscribe", arg0, arg1, arg2)
 }
 
 // Subscribe indicates an expected call of Subscribe
-func (mr *MockSubscriptionServiceMockRecorder) Subscribe(arg0, arg1 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockSubscriptionService)(nil).Subscribe), arg0, arg1)
+func (mr *MockSubscriptionServiceMockRecorder) Subscribe(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockSubscriptionService)(nil).Subscribe), arg0, arg1, arg2)
 }
 
 // Unsubscribe mocks base method
@@ -78,8 +78,10 @@ func (m *MockOnMessager) EXPECT() *MockOnMessagerMockRecorder {
 }
 
 // OnMessage mocks base method
-func (m *MockOnMessager) OnMessage(arg0 munch.ClientID, arg1 json.RawMessage) {
-	m.ctrl.Call(m, "OnMessage", arg0, arg1)
+func (m *MockOnMessager) OnMessage(arg0 munch.ClientID, arg1 json.RawMessage) error {
+	ret := m.ctrl.Call(m, "OnMessage", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // OnMessage indicates an expected call of OnMessage
@@ -6,39 +6,103 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"reflect"
 
 	"github.com/szabba/munch"
 	"github.com/szabba/munch/tagjson"
 )
 
+// IDKey is where clients put the ID of a message, next to its tag:
+//
+//	{"id": "42", "subscribe/v1": {...}}
+const IDKey = "id"
+
+// A Mux passes each message to the handler for its type. The types are told
+// apart by the tags they are registered with.
+//
+// Every message gets exactly one reply sent back to the client: an Ack when
+// the handler succeeds, and a Reject otherwise. The reply carries the ID of
+// the message, if it had one.
 type Mux struct {
-	tagHandlers map[string]OnMessager
+	reg         *tagjson.Registry
+	tagHandlers map[tagjson.TypeTag]OnMessager
+	replies     Replier
 }
 
-func NewMux(hs map[reflect.Type]OnMessager) *Mux {
-	tagHandlers := make(map[string]OnMessager)
+// NewMux creates a mux with handlers for the message types. It panics when a
+// type is missing from the registry.
+func NewMux(reg *tagjson.Registry, hs map[reflect.Type]OnMessager, replies Replier) *Mux {
+	tagHandlers := make(map[tagjson.TypeTag]OnMessager)
 	for typ, h := range hs {
-		tagHandlers[typ.String()] = h
+		tag, ok := reg.TagOf(typ)
+		if !ok {
+			panic(fmt.Sprintf("handlers: message type %s has no registered tag", typ))
+		}
+		tagHandlers[tag] = h
 	}
-	return &Mux{tagHandlers}
+	return &Mux{reg, tagHandlers, replies}
 }
 
 var _ OnMessager = new(Mux)
 
-func (mux *Mux) OnMessage(id munch.ClientID, msg json.RawMessage) {
-	tag, inner, err := tagjson.Untag(msg)
+// OnMessage only returns the error it already sent to the client.
+func (mux *Mux) OnMessage(id munch.ClientID, msg json.RawMessage) error {
+	reply := mux.handle(id, msg)
+	mux.replies.Reply(id, reply)
+	if rej, ok := reply.(Reject); ok {
+		return fmt.Errorf("%s: %s", rej.Code, rej.Message)
+	}
+	return nil
+}
+
+func (mux *Mux) handle(id munch.ClientID, msg json.RawMessage) interface{} {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(msg, &fields)
+	if err != nil || fields == nil {
+		return Reject{Code: RejectInvalid, Message: "message is not a JSON object"}
+	}
+
+	var msgID string
+	if rawID, ok := fields[IDKey]; ok {
+		err = json.Unmarshal(rawID, &msgID)
+		if err != nil {
+			return Reject{Code: RejectInvalid, Message: "message ID is not a string"}
+		}
+		delete(fields, IDKey)
+	}
+	if len(fields) != 1 {
+		return Reject{ID: msgID, Code: RejectInvalid, Message: fmt.Sprintf("message has %d tags instead of one", len(fields))}
+	}
+
+	for rawTag, inner := range fields {
+		return mux.dispatch(id, msgID, rawTag, inner)
+	}
+	panic("unreachable")
+}
+
+func (mux *Mux) dispatch(id munch.ClientID, msgID string, rawTag string, inner json.RawMessage) interface{} {
+	tag, err := tagjson.ParseTypeTag(rawTag)
 	if err != nil {
-		log.Printf("client %s sent invalid message: %s", id, msg)
-		return
+		return Reject{ID: msgID, Code: RejectInvalid, Message: err.Error()}
 	}
 
 	h := mux.tagHandlers[tag]
 	if h == nil {
-		log.Printf("client %s sent message with unexpected tag: %s", id, tag)
-		return
+		_, err := mux.reg.Lookup(tag)
+		switch {
+		case err == nil:
+			return Reject{ID: msgID, Code: RejectNotHandled, Message: fmt.Sprintf("%s messages are not handled", tag)}
+		case len(mux.reg.Versions(tag.Name)) == 0:
+			return Reject{ID: msgID, Code: RejectUnknownTag, Message: err.Error()}
+		default:
+			return Reject{ID: msgID, Code: RejectUnsupportedVersion, Message: err.Error()}
+		}
 	}
 
-	h.OnMessage(id, inner)
+	err = h.OnMessage(id, inner)
+	if err != nil {
+		return Reject{ID: msgID, Code: RejectFailed, Message: err.Error()}
+	}
+	return Ack{ID: msgID}
 }
@@ -6,6 +6,7 @@ package handlers_test
 
 import (
 	"encoding/json"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -13,24 +14,35 @@ import (
 	"github.com/szabba/munch"
 
 	"github.com/szabba/munch/handlers"
+	"github.com/szabba/munch/tagjson"
 )
 
 type MsgA struct{}
 type MsgB struct{}
 
+var Registry = newRegistry()
+
+func newRegistry() *tagjson.Registry {
+	reg := tagjson.NewRegistry()
+	reg.Register(tagjson.TypeTag{Name: "a", Version: 1}, MsgA{})
+	reg.Register(tagjson.TypeTag{Name: "b", Version: 1}, MsgB{})
+	return reg
+}
+
 var ClientID = new(munch.ClientIDGenerator).NextID()
 
 func TestMuxDelegatesToMatchingHandler(t *testing.T) {
 	// given
 	matching := new(CaptureHandler)
 
-	mux := handlers.NewMux(map[reflect.Type]handlers.OnMessager{
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
 		reflect.TypeOf(MsgA{}): matching,
 		reflect.TypeOf(MsgB{}): handlers.Discard(),
-	})
+	}, replies)
 
 	// when
-	mux.OnMessage(ClientID, json.RawMessage(`{"handlers_test.MsgA": {}}`))
+	mux.OnMessage(ClientID, json.RawMessage(`{"a/v1": {}}`))
 
 	// then
 	assert.That(matching.WasCalled(), t.Fatalf, "matching handler was not called")
@@ -38,19 +50,21 @@ func TestMuxDelegatesToMatchingHandler(t *testing.T) {
 	assert.That(
 		matching.MessageText() == `{}`,
 		t.Errorf, "got inner message %q, want %q", matching.MessageText(), `{}`)
+	assertReply(t, replies, handlers.Ack{})
 }
 
 func TestMuxDoesNotCallNonMatchingHandler(t *testing.T) {
 	// given
 	matching := new(CaptureHandler)
 
-	mux := handlers.NewMux(map[reflect.Type]handlers.OnMessager{
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
 		reflect.TypeOf(MsgA{}): handlers.Discard(),
 		reflect.TypeOf(MsgB{}): matching,
-	})
+	}, replies)
 
 	// when
-	mux.OnMessage(ClientID, json.RawMessage(`{"handlers_test.MsgA": {}}`))
+	mux.OnMessage(ClientID, json.RawMessage(`{"a/v1": {}}`))
 
 	// then
 	assert.That(!matching.WasCalled(), t.Fatalf, "non-matching handler was called")
@@ -60,58 +74,181 @@ func TestMuxDoesNotCallHandlerForInvalidMessage(t *testing.T) {
 	// given
 	capt := new(CaptureHandler)
 
-	mux := handlers.NewMux(map[reflect.Type]handlers.OnMessager{
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
 		reflect.TypeOf(MsgA{}): capt,
-	})
+	}, replies)
 
 	// when
 	mux.OnMessage(ClientID, json.RawMessage(`{{{`))
 
 	// then
 	assert.That(!capt.WasCalled(), t.Fatalf, "inner handler was unexpectedly called")
+	assertRejected(t, replies, handlers.RejectInvalid)
 }
 
 func TestMuxDoesNotCallHandlerForANonObjectMessage(t *testing.T) {
 	// given
 	capt := new(CaptureHandler)
 
-	mux := handlers.NewMux(map[reflect.Type]handlers.OnMessager{
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
 		reflect.TypeOf(MsgA{}): capt,
-	})
+	}, replies)
 
 	// when
 	mux.OnMessage(ClientID, json.RawMessage(`[1, 2, 3]`))
 
 	// then
 	assert.That(!capt.WasCalled(), t.Fatalf, "inner handler was unexpectedly called")
+	assertRejected(t, replies, handlers.RejectInvalid)
 }
 
 func TestMuxDoesNotCallHandlerForAMultipleFieldObjectMessage(t *testing.T) {
 	// given
 	capt := new(CaptureHandler)
 
-	mux := handlers.NewMux(map[reflect.Type]handlers.OnMessager{
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
+		reflect.TypeOf(MsgA{}): capt,
+	}, replies)
+
+	// when
+	mux.OnMessage(ClientID, json.RawMessage(`{"a/v1": {}, "b/v1": {}}`))
+
+	// then
+	assert.That(!capt.WasCalled(), t.Fatalf, "inner handler was unexpectedly called")
+	assertRejected(t, replies, handlers.RejectInvalid)
+}
+
+func TestMuxRejectsMessageWithUnknownTag(t *testing.T) {
+	// given
+	capt := new(CaptureHandler)
+
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
+		reflect.TypeOf(MsgA{}): capt,
+	}, replies)
+
+	// when
+	mux.OnMessage(ClientID, json.RawMessage(`{"c/v1": {}}`))
+
+	// then
+	assert.That(!capt.WasCalled(), t.Fatalf, "inner handler was unexpectedly called")
+	assertRejected(t, replies, handlers.RejectUnknownTag)
+}
+
+func TestMuxRejectsMessageWithUnsupportedVersion(t *testing.T) {
+	// given
+	capt := new(CaptureHandler)
+
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
+		reflect.TypeOf(MsgA{}): capt,
+	}, replies)
+
+	// when
+	mux.OnMessage(ClientID, json.RawMessage(`{"a/v2": {}}`))
+
+	// then
+	assert.That(!capt.WasCalled(), t.Fatalf, "inner handler was unexpectedly called")
+	assertRejected(t, replies, handlers.RejectUnsupportedVersion)
+}
+
+func TestMuxRejectsMessageWithUnversionedTag(t *testing.T) {
+	// given
+	capt := new(CaptureHandler)
+
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
 		reflect.TypeOf(MsgA{}): capt,
-	})
+	}, replies)
 
 	// when
-	mux.OnMessage(ClientID, json.RawMessage(`{"handlers_test.MsgA": {}, "handlers_test.MsgB": {}}`))
+	mux.OnMessage(ClientID, json.RawMessage(`{"a": {}}`))
 
 	// then
 	assert.That(!capt.WasCalled(), t.Fatalf, "inner handler was unexpectedly called")
+	assertRejected(t, replies, handlers.RejectInvalid)
 }
 
-func TestMuxIgnoresMessageWithUnknownTag(t *testing.T) {
+func TestMuxRepliesWithMessageID(t *testing.T) {
 	// given
 	capt := new(CaptureHandler)
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
+		reflect.TypeOf(MsgA{}): capt,
+	}, replies)
+
+	// when
+	err := mux.OnMessage(ClientID, json.RawMessage(`{"id": "42", "a/v1": {}}`))
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(capt.WasCalled(), t.Fatalf, "handler was not called")
+	assert.That(capt.MessageText() == `{}`, t.Errorf, "got inner message %q, want %q", capt.MessageText(), `{}`)
+	assertReply(t, replies, handlers.Ack{ID: "42"})
+}
+
+func TestMuxRejectsMessageTheHandlerFailedOn(t *testing.T) {
+	// given
+	capt := &CaptureHandler{err: errors.New("boom")}
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
+		reflect.TypeOf(MsgA{}): capt,
+	}, replies)
+
+	// when
+	err := mux.OnMessage(ClientID, json.RawMessage(`{"id": "42", "a/v1": {}}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assertReply(t, replies, handlers.Reject{ID: "42", Code: handlers.RejectFailed, Message: "boom"})
+}
+
+func TestMuxRejectsMessageWithoutHandler(t *testing.T) {
+	// given
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
+		reflect.TypeOf(MsgA{}): handlers.Discard(),
+	}, replies)
+
+	// when
+	mux.OnMessage(ClientID, json.RawMessage(`{"id": "7", "b/v1": {}}`))
+
+	// then
+	assertRejected(t, replies, handlers.RejectNotHandled)
+	reject := replies.Last().(handlers.Reject)
+	assert.That(reject.ID == "7", t.Errorf, "got reply ID %q, want %q", reject.ID, "7")
+}
 
-	mux := handlers.NewMux(map[reflect.Type]handlers.OnMessager{
+func TestMuxRejectsMessageWithNonStringID(t *testing.T) {
+	// given
+	capt := new(CaptureHandler)
+	replies := new(CaptureReplier)
+	mux := handlers.NewMux(Registry, map[reflect.Type]handlers.OnMessager{
 		reflect.TypeOf(MsgA{}): capt,
-	})
+	}, replies)
 
 	// when
-	mux.OnMessage(ClientID, json.RawMessage(`{"handlers_test.MsgC": {}}`))
+	mux.OnMessage(ClientID, json.RawMessage(`{"id": 42, "a/v1": {}}`))
 
 	// then
 	assert.That(!capt.WasCalled(), t.Fatalf, "inner handler was unexpectedly called")
+	assertRejected(t, replies, handlers.RejectInvalid)
+}
+
+func assertReply(t *testing.T, replies *CaptureReplier, want interface{}) {
+	t.Helper()
+	assert.That(len(replies.sent) == 1, t.Fatalf, "got %d replies, want %d", len(replies.sent), 1)
+	assert.That(replies.ids[0] == ClientID, t.Errorf, "got reply sent to %s, want %s", replies.ids[0], ClientID)
+	assert.That(replies.Last() == want, t.Errorf, "got reply %#v, want %#v", replies.Last(), want)
+}
+
+func assertRejected(t *testing.T, replies *CaptureReplier, code string) {
+	t.Helper()
+	assert.That(len(replies.sent) == 1, t.Fatalf, "got %d replies, want %d", len(replies.sent), 1)
+	reject, ok := replies.Last().(handlers.Reject)
+	assert.That(ok, t.Fatalf, "got reply %#v, want a rejection", replies.Last())
+	assert.That(reject.Code == code, t.Errorf, "got rejection code %q, want %q", reject.Code, code)
 }
@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// A PageQuery asks an HTTP endpoint for a page of events. It is read from the
+// URL query:
+//
+//	source  only events from the source, can be repeated
+//	since   only events at or after the RFC 3339 time
+//	until   only events before the RFC 3339 time
+//	q       only events whose message contains the text
+//	limit   at most this many events (100 by default, 1000 at most)
+//	cursor  continue from the end of an earlier page
+//
+// What a cursor looks like is up to the endpoint.
+type PageQuery struct {
+	Sources  []string
+	Contains string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+	Cursor   string
+}
+
+func ParsePageQuery(vals url.Values) (PageQuery, error) {
+	q := PageQuery{
+		Sources:  vals["source"],
+		Contains: vals.Get("q"),
+		Limit:    DefaultPageLimit,
+		Cursor:   vals.Get("cursor"),
+	}
+
+	var err error
+	q.Since, err = parseTime(vals, "since")
+	if err != nil {
+		return PageQuery{}, err
+	}
+	q.Until, err = parseTime(vals, "until")
+	if err != nil {
+		return PageQuery{}, err
+	}
+
+	if raw := vals.Get("limit"); raw != "" {
+		q.Limit, err = strconv.Atoi(raw)
+		if err != nil || q.Limit <= 0 {
+			return PageQuery{}, fmt.Errorf("limit must be a positive integer, got %q", raw)
+		}
+		if q.Limit > MaxPageLimit {
+			q.Limit = MaxPageLimit
+		}
+	}
+	return q, nil
+}
+
+func parseTime(vals url.Values, key string) (time.Time, error) {
+	raw := vals.Get(key)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time, got %q", key, raw)
+	}
+	return t, nil
+}
+
+type page struct {
+	Events []json.RawMessage `json:"events"`
+	Next   string            `json:"next,omitempty"`
+}
+
+// WritePage responds with the events, each formatted as for a websocket
+// client, and the cursor for the next page when there is one.
+func WritePage(w http.ResponseWriter, fmtr MessageFormatter, evts []munch.Event, next string) {
+	out := page{Events: make([]json.RawMessage, 0, len(evts)), Next: next}
+	for _, evt := range evts {
+		var buf bytes.Buffer
+		err := fmtr.FormatMessage(&buf, evt)
+		if err != nil {
+			log.Printf("cannot format event for a page: %s", err)
+			http.Error(w, "cannot format events", http.StatusInternalServerError)
+			return
+		}
+		out.Events = append(out.Events, bytes.TrimRight(buf.Bytes(), "\n"))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(out)
+	if err != nil {
+		log.Printf("cannot write page of events: %s", err)
+	}
+}
@@ -0,0 +1,35 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package handlers
+
+import "github.com/szabba/munch"
+
+// Why a message was rejected.
+const (
+	RejectInvalid            = "invalid-message"
+	RejectUnknownTag         = "unknown-tag"
+	RejectUnsupportedVersion = "unsupported-version"
+	RejectNotHandled         = "not-handled"
+	RejectFailed             = "failed"
+)
+
+// A Replier sends replies to single clients. It must not drop any, however
+// far behind a client is.
+type Replier interface {
+	Reply(id munch.ClientID, msg interface{})
+}
+
+// An Ack tells a client that the message with the ID was handled.
+type Ack struct {
+	ID string `json:"id"`
+}
+
+// A Reject tells a client that the message with the ID was not handled, and
+// why. The ID is empty when the message was too broken to have one.
+type Reject struct {
+	ID      string `json:"id"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
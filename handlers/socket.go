@@ -6,10 +6,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -20,25 +24,66 @@ type ClientIDFactory interface {
 	NextID() munch.ClientID
 }
 
+// A SubscriptionService sends messages to subscribed clients. It calls
+// disconnect when it drops a client it can no longer serve.
 type SubscriptionService interface {
-	Subscribe(munch.ClientID, func(interface{}))
+	Subscribe(id munch.ClientID, send func(interface{}), disconnect func(reason error))
 	Unsubscribe(munch.ClientID)
 }
 
+// An OnMessager handles messages sent by clients. The message is passed on as
+// the client sent it, so it need not even be valid JSON.
 type OnMessager interface {
-	OnMessage(id munch.ClientID, msg json.RawMessage)
+	OnMessage(id munch.ClientID, msg json.RawMessage) error
 }
 
 type MessageFormatter interface {
 	FormatMessage(w io.Writer, msg interface{}) error
 }
 
+// SocketOptions keep connections to clients healthy.
+//
+// The server pings each client every PingInterval. A client that sends
+// nothing, not even a pong, for IdleTimeout gets disconnected, as does one
+// that takes longer than WriteTimeout to accept a message, or that sends a
+// message longer than MaxMessageSize bytes.
+type SocketOptions struct {
+	PingInterval   time.Duration
+	IdleTimeout    time.Duration
+	WriteTimeout   time.Duration
+	MaxMessageSize int64
+}
+
+func DefaultSocketOptions() SocketOptions {
+	return SocketOptions{
+		PingInterval:   30 * time.Second,
+		IdleTimeout:    75 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		MaxMessageSize: 64 * 1024,
+	}
+}
+
+func (opts SocketOptions) Validate() error {
+	switch {
+	case opts.PingInterval <= 0:
+		return fmt.Errorf("ping interval must be positive, got %s", opts.PingInterval)
+	case opts.IdleTimeout <= opts.PingInterval:
+		return fmt.Errorf("idle timeout must be longer than the ping interval, got %s", opts.IdleTimeout)
+	case opts.WriteTimeout <= 0:
+		return fmt.Errorf("write timeout must be positive, got %s", opts.WriteTimeout)
+	case opts.MaxMessageSize <= 0:
+		return fmt.Errorf("maximum message size must be positive, got %d", opts.MaxMessageSize)
+	}
+	return nil
+}
+
 type Socket struct {
 	upgrader websocket.Upgrader
 	ids      ClientIDFactory
 	onMsg    OnMessager
 	fmtr     MessageFormatter
 	subs     SubscriptionService
+	opts     SocketOptions
 }
 
 func NewSocket(
@@ -49,7 +94,12 @@ func NewSocket(
 	subs SubscriptionService,
 ) *Socket {
 
-	return &Socket{upgrader, ids, onMsg, fmtr, subs}
+	return &Socket{upgrader, ids, onMsg, fmtr, subs, DefaultSocketOptions()}
+}
+
+// SetOptions changes the options for the connections accepted afterwards.
+func (h *Socket) SetOptions(opts SocketOptions) {
+	h.opts = opts
 }
 
 func (h *Socket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -61,53 +111,125 @@ func (h *Socket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer conn.Close()
 
 	id := h.ids.NextID()
-	sndr := newSender(id, h.fmtr, conn)
-	h.subs.Subscribe(id, sndr.send)
+	cl := newClient(id, conn)
+	sndr := newSender(cl, h.fmtr, h.opts.WriteTimeout)
+	h.subs.Subscribe(id, sndr.send, cl.disconnect)
 	defer h.subs.Unsubscribe(id)
 
-	h.readLoop(id, conn)
+	conn.SetReadLimit(h.opts.MaxMessageSize)
+	h.extendDeadline(conn)
+	conn.SetPongHandler(func(string) error {
+		h.extendDeadline(conn)
+		return nil
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go h.pingLoop(cl, done)
+
+	err = h.readLoop(id, conn)
+	cl.disconnect(err)
 }
 
-func (h *Socket) readLoop(id munch.ClientID, conn *websocket.Conn) {
+func (h *Socket) readLoop(id munch.ClientID, conn *websocket.Conn) error {
 	for {
 		_, r, err := conn.NextReader()
 		if err != nil {
-			log.Printf("client %s read error: %s", id, err)
+			return readError(err)
+		}
+		h.extendDeadline(conn)
+		msg, err := ioutil.ReadAll(r)
+		if err != nil {
+			return readError(err)
+		}
+		err = h.onMsg.OnMessage(id, msg)
+		if err != nil {
+			log.Printf("client %s sent message that was not handled: %s", id, err)
+		}
+	}
+}
+
+func (h *Socket) extendDeadline(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(h.opts.IdleTimeout))
+}
+
+func (h *Socket) pingLoop(cl *client, done <-chan struct{}) {
+	ticker := time.NewTicker(h.opts.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
 			return
+		case <-ticker.C:
 		}
-		var msg json.RawMessage
-		err = json.NewDecoder(r).Decode(&msg)
+		deadline := time.Now().Add(h.opts.WriteTimeout)
+		err := cl.conn.WriteControl(websocket.PingMessage, nil, deadline)
 		if err != nil {
-			log.Printf("client %s sent invalid message: %s", id, err)
-			break
+			cl.disconnect(fmt.Errorf("cannot ping: %s", err))
+			return
 		}
-		h.onMsg.OnMessage(id, msg)
 	}
 }
 
-type sender struct {
-	lock sync.Mutex
+func readError(err error) error {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return fmt.Errorf("idle for too long")
+	}
+	if err == websocket.ErrReadLimit {
+		return fmt.Errorf("sent a message over the size limit")
+	}
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return fmt.Errorf("closed the connection")
+	}
+	return err
+}
+
+// A client closes the connection once, logging the reason why.
+type client struct {
+	once sync.Once
 	id   munch.ClientID
-	fmtr MessageFormatter
 	conn *websocket.Conn
 }
 
-func newSender(id munch.ClientID, fmtr MessageFormatter, conn *websocket.Conn) *sender {
-	return &sender{id: id, conn: conn, fmtr: fmtr}
+func newClient(id munch.ClientID, conn *websocket.Conn) *client {
+	return &client{id: id, conn: conn}
+}
+
+func (cl *client) disconnect(reason error) {
+	cl.once.Do(func() {
+		log.Printf("client %s disconnected: %s", cl.id, reason)
+		cl.conn.Close()
+	})
+}
+
+type sender struct {
+	lock         sync.Mutex
+	client       *client
+	fmtr         MessageFormatter
+	writeTimeout time.Duration
+}
+
+func newSender(cl *client, fmtr MessageFormatter, writeTimeout time.Duration) *sender {
+	return &sender{client: cl, fmtr: fmtr, writeTimeout: writeTimeout}
 }
 
 func (s *sender) send(msg interface{}) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	w, err := s.conn.NextWriter(websocket.TextMessage)
+	conn := s.client.conn
+	conn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	w, err := conn.NextWriter(websocket.TextMessage)
 	if err != nil {
-		log.Printf("client %s write error: %s", s.id, err)
+		s.client.disconnect(fmt.Errorf("write error: %s", err))
 		return
 	}
-	defer w.Close()
 	err = s.fmtr.FormatMessage(w, msg)
 	if err != nil {
-		log.Printf("client %s write error: %s", s.id, err)
+		log.Printf("client %s write error: %s", s.client.id, err)
+	}
+	err = w.Close()
+	if err != nil {
+		s.client.disconnect(fmt.Errorf("write error: %s", err))
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/websocket"
@@ -31,7 +32,7 @@ func TestSocketSubscribesAndUsubscribesClient(t *testing.T) {
 	clientID := munch.ClientIDOf(0)
 
 	gomock.InOrder(
-		subsMock.EXPECT().Subscribe(clientID, gomock.Any()),
+		subsMock.EXPECT().Subscribe(clientID, gomock.Any(), gomock.Any()),
 		subsMock.EXPECT().Unsubscribe(clientID))
 
 	// when
@@ -53,7 +54,7 @@ func TestSocketPassesClientMessageToHandler(t *testing.T) {
 	send := make(chan string)
 	msgGot := ""
 	gomock.InOrder(
-		subsMock.EXPECT().Subscribe(clientID, gomock.Any()),
+		subsMock.EXPECT().Subscribe(clientID, gomock.Any(), gomock.Any()),
 		onMsgMock.EXPECT().OnMessage(clientID, gomock.Any()).
 			Do(func(_ munch.ClientID, msg json.RawMessage) { send <- string(msg) }),
 		subsMock.EXPECT().Unsubscribe(clientID))
@@ -88,8 +89,8 @@ func TestSocketLetsTheSubscriptionServiceSendMessagesToTheClient(t *testing.T) {
 	)
 
 	gomock.InOrder(
-		subsMock.EXPECT().Subscribe(clientID, gomock.Any()).
-			Do(func(_ munch.ClientID, f func(interface{})) { sem <- f }),
+		subsMock.EXPECT().Subscribe(clientID, gomock.Any(), gomock.Any()).
+			Do(func(_ munch.ClientID, f func(interface{}), _ func(error)) { sem <- f }),
 		subsMock.EXPECT().Unsubscribe(clientID))
 
 	conn, err := connect(srv)
@@ -152,3 +153,118 @@ func assumeNoError(t *testing.T, err error) {
 func assertNoError(t *testing.T, err error) {
 	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
 }
+
+func TestSocketDisconnectsClientThatDoesNotAnswerPings(t *testing.T) {
+	// given
+	subs := NewUnsubscribeRecorder()
+	srv := startServerWithOptions(new(CaptureHandler), SprintFormatter{}, subs, handlers.SocketOptions{
+		PingInterval:   10 * time.Millisecond,
+		IdleTimeout:    50 * time.Millisecond,
+		WriteTimeout:   time.Second,
+		MaxMessageSize: 1024,
+	})
+	defer srv.Close()
+
+	// when
+	conn, err := connect(srv)
+	assumeNoError(t, err)
+	defer conn.Close()
+
+	// then
+	subs.AssertUnsubscribed(t)
+}
+
+func TestSocketKeepsClientThatAnswersPings(t *testing.T) {
+	// given
+	subs := NewUnsubscribeRecorder()
+	srv := startServerWithOptions(new(CaptureHandler), SprintFormatter{}, subs, handlers.SocketOptions{
+		PingInterval:   10 * time.Millisecond,
+		IdleTimeout:    50 * time.Millisecond,
+		WriteTimeout:   time.Second,
+		MaxMessageSize: 1024,
+	})
+	defer srv.Close()
+
+	conn, err := connect(srv)
+	assumeNoError(t, err)
+	defer conn.Close()
+
+	// when
+	go func() {
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// then
+	subs.AssertNotUnsubscribed(t, 200*time.Millisecond)
+}
+
+func TestSocketDisconnectsClientSendingTooLongMessage(t *testing.T) {
+	// given
+	subs := NewUnsubscribeRecorder()
+	opts := handlers.DefaultSocketOptions()
+	opts.MaxMessageSize = 8
+	srv := startServerWithOptions(new(CaptureHandler), SprintFormatter{}, subs, opts)
+	defer srv.Close()
+
+	conn, err := connect(srv)
+	assumeNoError(t, err)
+	defer conn.Close()
+
+	// when
+	err = conn.WriteMessage(websocket.TextMessage, []byte(`"a long message"`))
+	assertNoError(t, err)
+
+	// then
+	subs.AssertUnsubscribed(t)
+}
+
+func startServerWithOptions(
+	onMsg handlers.OnMessager,
+	fmtr handlers.MessageFormatter,
+	subs handlers.SubscriptionService,
+	opts handlers.SocketOptions,
+) *httptest.Server {
+
+	up := websocket.Upgrader{}
+	idGen := new(munch.ClientIDGenerator)
+	h := handlers.NewSocket(up, idGen, onMsg, fmtr, subs)
+	h.SetOptions(opts)
+	return httptest.NewServer(h)
+}
+
+type UnsubscribeRecorder struct {
+	unsubscribed chan munch.ClientID
+}
+
+var _ handlers.SubscriptionService = new(UnsubscribeRecorder)
+
+func NewUnsubscribeRecorder() *UnsubscribeRecorder {
+	return &UnsubscribeRecorder{unsubscribed: make(chan munch.ClientID, 1)}
+}
+
+func (r *UnsubscribeRecorder) Subscribe(munch.ClientID, func(interface{}), func(error)) {}
+
+func (r *UnsubscribeRecorder) Unsubscribe(id munch.ClientID) { r.unsubscribed <- id }
+
+func (r *UnsubscribeRecorder) AssertUnsubscribed(t *testing.T) {
+	t.Helper()
+	select {
+	case <-r.unsubscribed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("client was not unsubscribed")
+	}
+}
+
+func (r *UnsubscribeRecorder) AssertNotUnsubscribed(t *testing.T, wait time.Duration) {
+	t.Helper()
+	select {
+	case id := <-r.unsubscribed:
+		t.Errorf("client %s was unsubscribed", id)
+	case <-time.After(wait):
+	}
+}
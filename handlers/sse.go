@@ -0,0 +1,253 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+// SSEOptions configure an SSE handler. Backlog is how many recent messages are
+// kept for clients reconnecting, and Buffer how many can wait to be written to
+// a connected client. Every KeepAlive a comment gets sent to idle clients.
+type SSEOptions struct {
+	Backlog   int
+	Buffer    int
+	KeepAlive time.Duration
+}
+
+func DefaultSSEOptions() SSEOptions {
+	return SSEOptions{
+		Backlog:   1000,
+		Buffer:    256,
+		KeepAlive: 30 * time.Second,
+	}
+}
+
+func (opts SSEOptions) Validate() error {
+	switch {
+	case opts.Backlog <= 0:
+		return fmt.Errorf("backlog must be positive, got %d", opts.Backlog)
+	case opts.Buffer <= 0:
+		return fmt.Errorf("buffer must be positive, got %d", opts.Buffer)
+	case opts.KeepAlive <= 0:
+		return fmt.Errorf("keep alive interval must be positive, got %s", opts.KeepAlive)
+	}
+	return nil
+}
+
+// An SSE handler streams messages as server-sent events. It subscribes to the
+// subscription service as a single client and shares what it receives with
+// all the connections it serves.
+//
+// Each message gets a sequential ID. A client reconnecting with the
+// Last-Event-ID header gets the messages it missed, as far as the backlog
+// reaches. Other clients only get messages sent after they connect. A client
+// that falls behind by more than the buffer gets disconnected, so that it can
+// reconnect and catch up from the backlog.
+type SSE struct {
+	lock    sync.Mutex
+	id      munch.ClientID
+	fmtr    MessageFormatter
+	subs    LiveSubscriptionService
+	opts    SSEOptions
+	seq     uint64
+	backlog []sseEvent
+	conns   map[*sseConn]bool
+
+	disconnects int
+	reportedAt  time.Time
+}
+
+// A LiveSubscriptionService can also subscribe a client without replaying
+// the messages sent before.
+type LiveSubscriptionService interface {
+	SubscriptionService
+	SubscribeLive(id munch.ClientID, send func(interface{}), disconnect func(reason error))
+}
+
+const sseReportInterval = time.Minute
+
+type sseEvent struct {
+	id   uint64
+	data []byte
+}
+
+type sseConn struct {
+	events  chan sseEvent
+	dropped chan struct{}
+}
+
+func NewSSE(ids ClientIDFactory, fmtr MessageFormatter, subs LiveSubscriptionService, opts SSEOptions) *SSE {
+	h := &SSE{
+		id:    ids.NextID(),
+		fmtr:  fmtr,
+		subs:  subs,
+		opts:  opts,
+		conns: make(map[*sseConn]bool),
+	}
+	h.subscribe()
+	return h
+}
+
+func (h *SSE) subscribe() {
+	h.subs.Subscribe(h.id, h.receive, h.resubscribe)
+}
+
+// resubscribe skips the replay of recent messages, as they are in the backlog
+// already. The disconnects only get logged once in a while, as a slow handler
+// can get disconnected over and over.
+func (h *SSE) resubscribe(reason error) {
+	h.lock.Lock()
+	h.disconnects++
+	now := time.Now()
+	report := now.Sub(h.reportedAt) >= sseReportInterval
+	disconnects := h.disconnects
+	if report {
+		h.reportedAt = now
+		h.disconnects = 0
+	}
+	h.lock.Unlock()
+
+	if report {
+		log.Printf("SSE handler got disconnected %d time(s) since the last report, subscribing again: %s", disconnects, reason)
+	}
+	h.subs.SubscribeLive(h.id, h.receive, h.resubscribe)
+}
+
+// Close unsubscribes the handler.
+func (h *SSE) Close() {
+	h.subs.Unsubscribe(h.id)
+}
+
+func (h *SSE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, missed := h.connect(lastEventID(r))
+	defer h.disconnect(conn)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, evt := range missed {
+		writeSSEEvent(w, evt)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(h.opts.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-conn.dropped:
+			log.Printf("SSE client %s fell behind, disconnecting", r.RemoteAddr)
+			return
+		case evt := <-conn.events:
+			writeSSEEvent(w, evt)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func (h *SSE) receive(msg interface{}) {
+	var buf bytes.Buffer
+	err := h.fmtr.FormatMessage(&buf, msg)
+	if err != nil {
+		log.Printf("cannot format server-sent event: %s", err)
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.seq++
+	evt := sseEvent{id: h.seq, data: bytes.TrimRight(buf.Bytes(), "\n")}
+	if len(h.backlog) == h.opts.Backlog {
+		h.backlog[0] = sseEvent{}
+		h.backlog = h.backlog[1:]
+	}
+	h.backlog = append(h.backlog, evt)
+
+	for conn := range h.conns {
+		select {
+		case conn.events <- evt:
+		default:
+			delete(h.conns, conn)
+			close(conn.dropped)
+		}
+	}
+}
+
+// connect registers a new connection. When the client saw some events before,
+// it returns the ones sent since, that are still in the backlog.
+func (h *SSE) connect(lastID uint64) (*sseConn, []sseEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	conn := &sseConn{
+		events:  make(chan sseEvent, h.opts.Buffer),
+		dropped: make(chan struct{}),
+	}
+	h.conns[conn] = true
+
+	if lastID == 0 {
+		return conn, nil
+	}
+	if lastID > h.seq {
+		// The ID comes from before a restart, so everything is news.
+		lastID = 0
+	}
+	var missed []sseEvent
+	for _, evt := range h.backlog {
+		if evt.id > lastID {
+			missed = append(missed, evt)
+		}
+	}
+	return conn, missed
+}
+
+func (h *SSE) disconnect(conn *sseConn) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.conns, conn)
+}
+
+func lastEventID(r *http.Request) uint64 {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("lastEventId")
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+func writeSSEEvent(w http.ResponseWriter, evt sseEvent) {
+	fmt.Fprintf(w, "id: %d\n", evt.id)
+	for _, line := range bytes.Split(evt.data, []byte("\n")) {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -0,0 +1,204 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/handlers"
+)
+
+func TestSSEStreamsMessagesWithIDs(t *testing.T) {
+	// given
+	subs := new(SenderCapture)
+	h := handlers.NewSSE(new(munch.ClientIDGenerator), SprintFormatter{}, subs, handlers.DefaultSSEOptions())
+	defer h.Close()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	events, stop := streamEvents(t, srv.URL, "")
+	defer stop()
+
+	// when
+	subs.Send("first")
+	subs.Send("second\nline")
+
+	// then
+	expectEvent(t, events, "id: 1\ndata: first\n")
+	expectEvent(t, events, "id: 2\ndata: second\ndata: line\n")
+}
+
+func TestSSESendsMissedMessagesToReconnectingClient(t *testing.T) {
+	// given
+	subs := new(SenderCapture)
+	h := handlers.NewSSE(new(munch.ClientIDGenerator), SprintFormatter{}, subs, handlers.DefaultSSEOptions())
+	defer h.Close()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	subs.Send("first")
+	subs.Send("second")
+	subs.Send("third")
+
+	// when
+	events, stop := streamEvents(t, srv.URL, "1")
+	defer stop()
+
+	// then
+	expectEvent(t, events, "id: 2\ndata: second\n")
+	expectEvent(t, events, "id: 3\ndata: third\n")
+}
+
+func TestSSEOnlySendsNewMessagesToNewClient(t *testing.T) {
+	// given
+	subs := new(SenderCapture)
+	h := handlers.NewSSE(new(munch.ClientIDGenerator), SprintFormatter{}, subs, handlers.DefaultSSEOptions())
+	defer h.Close()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	subs.Send("old")
+	events, stop := streamEvents(t, srv.URL, "")
+	defer stop()
+
+	// when
+	subs.Send("new")
+
+	// then
+	expectEvent(t, events, "id: 2\ndata: new\n")
+}
+
+func TestSSEDoesNotRepeatMessagesAfterSubscribingAgain(t *testing.T) {
+	// given
+	subs := new(SenderCapture)
+	h := handlers.NewSSE(new(munch.ClientIDGenerator), SprintFormatter{}, subs, handlers.DefaultSSEOptions())
+	defer h.Close()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	subs.Send("first")
+	subs.Send("second")
+
+	// when
+	subs.Disconnect(errors.New("fell behind"))
+	subs.Send("third")
+	events, stop := streamEvents(t, srv.URL, "1")
+	defer stop()
+
+	// then
+	expectEvent(t, events, "id: 2\ndata: second\n")
+	expectEvent(t, events, "id: 3\ndata: third\n")
+}
+
+// A SenderCapture is a subscription service for a single client. Like the
+// real one, it replays the messages sent so far to each new subscription,
+// unless it is a live one.
+type SenderCapture struct {
+	lock       sync.Mutex
+	send       func(interface{})
+	disconnect func(error)
+	sent       []interface{}
+}
+
+var _ handlers.LiveSubscriptionService = new(SenderCapture)
+
+func (c *SenderCapture) Subscribe(_ munch.ClientID, send func(interface{}), disconnect func(error)) {
+	c.lock.Lock()
+	c.send, c.disconnect = send, disconnect
+	sent := append([]interface{}(nil), c.sent...)
+	c.lock.Unlock()
+	for _, msg := range sent {
+		send(msg)
+	}
+}
+
+func (c *SenderCapture) SubscribeLive(_ munch.ClientID, send func(interface{}), disconnect func(error)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.send, c.disconnect = send, disconnect
+}
+
+// Disconnect drops the subscriber, like the real service does with one that
+// falls behind.
+func (c *SenderCapture) Disconnect(reason error) {
+	c.lock.Lock()
+	disconnect := c.disconnect
+	c.lock.Unlock()
+	disconnect(reason)
+}
+
+func (c *SenderCapture) Unsubscribe(munch.ClientID) {}
+
+func (c *SenderCapture) Send(msg interface{}) {
+	c.lock.Lock()
+	c.sent = append(c.sent, msg)
+	send := c.send
+	c.lock.Unlock()
+	send(msg)
+}
+
+// streamEvents connects to an SSE endpoint and splits what it sends into
+// events. Comments get skipped. Calling stop disconnects.
+func streamEvents(t *testing.T, url, lastEventID string) (events <-chan string, stop func()) {
+	t.Helper()
+	ctx, stop := context.WithCancel(context.Background())
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assumeNoError(t, err)
+	req = req.WithContext(ctx)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assumeNoError(t, err)
+	assert.That(
+		resp.Header.Get("Content-Type") == "text/event-stream",
+		t.Fatalf, "got content type %q, want %q", resp.Header.Get("Content-Type"), "text/event-stream")
+
+	out := make(chan string)
+	go func() {
+		defer resp.Body.Close()
+		defer close(out)
+		scanner := bufio.NewScanner(resp.Body)
+		var evt strings.Builder
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "" && evt.Len() > 0:
+				select {
+				case out <- evt.String():
+				case <-ctx.Done():
+					return
+				}
+				evt.Reset()
+			case line == "" || strings.HasPrefix(line, ":"):
+			default:
+				evt.WriteString(line + "\n")
+			}
+		}
+	}()
+	return out, stop
+}
+
+func expectEvent(t *testing.T, events <-chan string, want string) {
+	t.Helper()
+	select {
+	case got, ok := <-events:
+		assert.That(ok, t.Fatalf, "stream ended, want event %q", want)
+		assert.That(got == want, t.Errorf, "got event %q, want %q", got, want)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for event %q", want)
+	}
+}
@@ -17,20 +17,36 @@ type CaptureHandler struct {
 	wasCalled bool
 	id        munch.ClientID
 	msg       string
+	err       error
 }
 
 var _ handlers.OnMessager = new(CaptureHandler)
 
-func (capt *CaptureHandler) OnMessage(id munch.ClientID, msg json.RawMessage) {
+func (capt *CaptureHandler) OnMessage(id munch.ClientID, msg json.RawMessage) error {
 	capt.wasCalled = true
 	capt.id = id
 	capt.msg = string(msg)
+	return capt.err
 }
 
 func (capt *CaptureHandler) WasCalled() bool     { return capt.wasCalled }
 func (capt *CaptureHandler) ID() munch.ClientID  { return capt.id }
 func (capt *CaptureHandler) MessageText() string { return capt.msg }
 
+type CaptureReplier struct {
+	ids  []munch.ClientID
+	sent []interface{}
+}
+
+var _ handlers.Replier = new(CaptureReplier)
+
+func (capt *CaptureReplier) Reply(id munch.ClientID, msg interface{}) {
+	capt.ids = append(capt.ids, id)
+	capt.sent = append(capt.sent, msg)
+}
+
+func (capt *CaptureReplier) Last() interface{} { return capt.sent[len(capt.sent)-1] }
+
 type SprintFormatter struct{}
 
 var _ handlers.MessageFormatter = SprintFormatter{}
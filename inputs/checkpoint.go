@@ -0,0 +1,174 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/szabba/munch/checkpoint"
+)
+
+// fileCheckInterval limits how often a file being read gets checked for
+// rotation and truncation.
+const fileCheckInterval = time.Second
+
+// resume opens the file at path with open, picking up where the checkpoint
+// saved for it says reading stopped. When the file has been replaced or
+// truncated since, it gets read from the beginning. Files without a checkpoint
+// start as they would otherwise.
+func resume(store *checkpoint.Store, path string, readAll bool, open func(path string, readAll bool) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &checkpointReader{store: store, path: path}
+	pos, saved := store.Get(path)
+	info, statErr := os.Stat(path)
+	if statErr == nil {
+		r.inode = inode(info)
+	}
+	switch {
+	case statErr != nil:
+		// Whatever shows up at the path later is new.
+		readAll = true
+	case saved && pos.Inode == r.inode && pos.Offset <= info.Size():
+		readAll, r.skip, r.offset = true, pos.Offset, pos.Offset
+	case saved:
+		log.Printf("file %s was rotated or truncated since the last checkpoint, reading it from the beginning", path)
+		readAll = true
+	case !readAll:
+		r.offset = info.Size()
+	}
+	if statErr == nil {
+		store.Set(path, checkpoint.Position{Inode: r.inode, Offset: r.offset})
+	}
+
+	r.input, err = open(path, readAll)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// A checkpointReader records how far into a file its input has got.
+//
+// The input reopens the path when the file gets rotated or truncated, at a
+// point the reader cannot see. So every so often the reader checks the lines it
+// read since the last check against the file at the path. When they are not
+// where it expected them to be, the offset starts over from the lines that
+// match the beginning of the file, which are the ones read from it after it
+// got reopened.
+type checkpointReader struct {
+	input     io.ReadCloser
+	store     *checkpoint.Store
+	path      string
+	inode     uint64
+	skip      int64
+	offset    int64
+	pending   int64
+	recent    []byte
+	lastCheck time.Time
+}
+
+func (r *checkpointReader) Read(p []byte) (int, error) {
+	err := r.skipSaved()
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.input.Read(p)
+	r.advance(p[:n])
+	return n, err
+}
+
+func (r *checkpointReader) Close() error {
+	return r.input.Close()
+}
+
+func (r *checkpointReader) skipSaved() error {
+	if r.skip == 0 {
+		return nil
+	}
+	skip := r.skip
+	r.skip = 0
+	if seeker, ok := r.input.(io.Seeker); ok {
+		_, err := seeker.Seek(skip, io.SeekStart)
+		return err
+	}
+	_, err := io.CopyN(ioutil.Discard, r.input, skip)
+	return err
+}
+
+func (r *checkpointReader) advance(data []byte) {
+	r.recent = append(r.recent, data...)
+	ix := bytes.LastIndexByte(data, '\n')
+	if ix == -1 {
+		r.pending += int64(len(data))
+		return
+	}
+	r.offset += r.pending + int64(ix+1)
+	r.pending = int64(len(data) - ix - 1)
+
+	r.checkFile()
+	r.store.Set(r.path, checkpoint.Position{Inode: r.inode, Offset: r.offset})
+}
+
+func (r *checkpointReader) checkFile() {
+	now := time.Now()
+	if now.Sub(r.lastCheck) < fileCheckInterval {
+		return
+	}
+	r.lastCheck = now
+
+	f, err := os.Open(r.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+
+	lines := r.recent[:len(r.recent)-int(r.pending)]
+	if inode(info) != r.inode || !readAt(f, r.offset-int64(len(lines)), lines) {
+		r.inode = inode(info)
+		r.offset = reopenedAt(f, lines)
+	}
+	r.recent = append(r.recent[:0], r.recent[len(lines):]...)
+}
+
+// reopenedAt returns how many of the lines read come from the beginning of f.
+// They are the longest run of lines at the end that f starts with.
+func reopenedAt(f *os.File, lines []byte) int64 {
+	start := make([]byte, len(lines))
+	n, _ := f.ReadAt(start, 0)
+	start = start[:n]
+	for i := 0; i < len(lines); i++ {
+		if i > 0 && lines[i-1] != '\n' {
+			continue
+		}
+		if bytes.HasPrefix(start, lines[i:]) {
+			return int64(len(lines) - i)
+		}
+	}
+	return 0
+}
+
+// readAt tells whether f has the data at the offset.
+func readAt(f *os.File, offset int64, data []byte) bool {
+	if offset < 0 {
+		return false
+	}
+	got := make([]byte, len(data))
+	n, _ := f.ReadAt(got, offset)
+	return bytes.Equal(got[:n], data)
+}
@@ -0,0 +1,164 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/checkpoint"
+	"github.com/szabba/munch/inputs"
+)
+
+func TestResumingFileInputPicksUpWhereTheLastRunStopped(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\nb\n")
+
+	readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtBeginning, false))
+	appendFile(t, path, "c\n")
+
+	// when
+	all := readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtBeginning, false))
+
+	// then
+	assert.That(all == "c\n", t.Errorf, "got %q, want %q", all, "c\n")
+}
+
+func TestResumingFileInputDoesNotDropLinesWrittenWhileDown(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\n")
+
+	readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtEnd, false))
+	appendFile(t, path, "b\n")
+
+	// when
+	all := readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtEnd, false))
+
+	// then
+	assert.That(all == "b\n", t.Errorf, "got %q, want %q", all, "b\n")
+}
+
+func TestResumingFileInputRereadsTruncatedFile(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\nb\n")
+
+	readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtBeginning, false))
+	writeFile(t, dir, "c\n")
+
+	// when
+	all := readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtBeginning, false))
+
+	// then
+	assert.That(all == "c\n", t.Errorf, "got %q, want %q", all, "c\n")
+}
+
+func TestResumingFileInputRereadsRotatedFile(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\n")
+
+	readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtBeginning, false))
+	err := os.Rename(path, path+".1")
+	assumeNoError(t, err)
+	writeFile(t, dir, "b\nc\n")
+
+	// when
+	all := readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtBeginning, false))
+
+	// then
+	assert.That(all == "b\nc\n", t.Errorf, "got %q, want %q", all, "b\nc\n")
+}
+
+func TestResumingFollowedFileInputSkipsLinesAlreadyRead(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\nb\n")
+
+	readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtBeginning, false))
+	appendFile(t, path, "c\n")
+
+	store := openCheckpoints(t, dir)
+	fact := inputs.NewResumingFileFactory(inputs.DefaultWatchOptions(), store)
+
+	// when
+	input, err := fact.NewInput("src", fileDef(path, inputs.StartAtBeginning, true))
+	assumeNoError(t, err)
+	defer input.Close()
+	lines := readLines(input)
+
+	// then
+	expectLine(t, lines, "c")
+}
+
+func TestResumingFollowedFileInputDoesNotReplayFileRotatedWhileRunning(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\n")
+
+	store := openCheckpoints(t, dir)
+	fact := inputs.NewResumingFileFactory(inputs.DefaultWatchOptions(), store)
+	input, err := fact.NewInput("src", fileDef(path, inputs.StartAtBeginning, true))
+	assumeNoError(t, err)
+	lines := readLines(input)
+	expectLine(t, lines, "a")
+
+	err = os.Rename(path, path+".1")
+	assumeNoError(t, err)
+	writeFile(t, dir, "b\n")
+	expectLine(t, lines, "b")
+	// Let the input check the file again, which it does every second.
+	time.Sleep(1100 * time.Millisecond)
+	appendFile(t, path, "c\n")
+	expectLine(t, lines, "c")
+
+	input.Close()
+	err = store.Save()
+	assumeNoError(t, err)
+	appendFile(t, path, "d\n")
+
+	// when
+	all := readWithCheckpoints(t, dir, fileDef(path, inputs.StartAtBeginning, false))
+
+	// then
+	assert.That(all == "d\n", t.Errorf, "got %q, want %q", all, "d\n")
+}
+
+func readWithCheckpoints(t *testing.T, dir string, def json.RawMessage) string {
+	t.Helper()
+	store := openCheckpoints(t, dir)
+	fact := inputs.NewResumingFileFactory(inputs.DefaultWatchOptions(), store)
+
+	input, err := fact.NewInput("src", def)
+	assumeNoError(t, err)
+	defer input.Close()
+	all, err := ioutil.ReadAll(input)
+	assumeNoError(t, err)
+
+	err = store.Save()
+	assumeNoError(t, err)
+	return string(all)
+}
+
+func openCheckpoints(t *testing.T, dir string) *checkpoint.Store {
+	t.Helper()
+	store, err := checkpoint.Open(filepath.Join(dir, "state"))
+	assumeNoError(t, err)
+	return store
+}
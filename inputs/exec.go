@@ -0,0 +1,167 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/szabba/munch/sources"
+)
+
+// An ExecDefinition describes a command to run and read the output of. Env
+// holds KEY=value pairs added to the environment munch runs in.
+type ExecDefinition struct {
+	Command []string `json:"command"`
+	Dir     string   `json:"dir"`
+	Env     []string `json:"env"`
+}
+
+// An Exec is an input running a command. The standard output and error of
+// the command are separate streams, named after the source with a ":stdout"
+// and ":stderr" suffix.
+//
+// Once both streams end and the command exits, reading an Exec gives an
+// ExitError, whatever the exit status was.
+type Exec struct {
+	once    sync.Once
+	cmd     *exec.Cmd
+	streams chan sources.Stream
+	done    chan struct{}
+	err     error
+}
+
+var _ sources.Fanout = new(Exec)
+
+// An ExitError reports that the command of an Exec input has exited. Err is
+// the error returned by exec.Cmd.Wait, if any.
+type ExitError struct {
+	Command string
+	Err     error
+}
+
+func (err *ExitError) Error() string {
+	if err.Err == nil {
+		return fmt.Sprintf("command %q exited", err.Command)
+	}
+	return fmt.Sprintf("command %q exited: %s", err.Command, err.Err)
+}
+
+// NewExec starts cmd, which must not have its standard output or error set.
+func NewExec(name string, cmd *exec.Cmd) (*Exec, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Exec{
+		cmd:     cmd,
+		streams: make(chan sources.Stream, 2),
+		done:    make(chan struct{}),
+	}
+
+	var pipes sync.WaitGroup
+	pipes.Add(2)
+	e.streams <- sources.Stream{Name: name + ":stdout", Input: newPipeReader(stdout, pipes.Done)}
+	e.streams <- sources.Stream{Name: name + ":stderr", Input: newPipeReader(stderr, pipes.Done)}
+	close(e.streams)
+
+	go e.wait(&pipes)
+	return e, nil
+}
+
+func (e *Exec) Streams() <-chan sources.Stream {
+	return e.streams
+}
+
+func (e *Exec) Read(p []byte) (int, error) {
+	<-e.done
+	return 0, e.err
+}
+
+// Close kills the command, unless it has already exited.
+func (e *Exec) Close() error {
+	e.once.Do(func() { e.cmd.Process.Kill() })
+	return nil
+}
+
+func (e *Exec) wait(pipes *sync.WaitGroup) {
+	defer close(e.done)
+	// The pipes get closed by Wait, so all reading has to be over before.
+	pipes.Wait()
+	e.err = &ExitError{
+		Command: strings.Join(e.cmd.Args, " "),
+		Err:     e.cmd.Wait(),
+	}
+}
+
+// A pipeReader reports when it reaches the end of the pipe or gets closed.
+type pipeReader struct {
+	once sync.Once
+	pipe io.ReadCloser
+	done func()
+}
+
+func newPipeReader(pipe io.ReadCloser, done func()) *pipeReader {
+	return &pipeReader{pipe: pipe, done: done}
+}
+
+func (r *pipeReader) Read(p []byte) (int, error) {
+	n, err := r.pipe.Read(p)
+	if err != nil {
+		r.once.Do(r.done)
+	}
+	return n, err
+}
+
+func (r *pipeReader) Close() error {
+	err := r.pipe.Close()
+	r.once.Do(r.done)
+	return err
+}
+
+func newExec(name string, rawDef json.RawMessage) (io.ReadCloser, error) {
+	def, err := parseExecDefinition(rawDef)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(def.Command[0], def.Command[1:]...)
+	cmd.Dir = def.Dir
+	if len(def.Env) > 0 {
+		cmd.Env = append(os.Environ(), def.Env...)
+	}
+	return NewExec(name, cmd)
+}
+
+func validateExec(_ string, rawDef json.RawMessage) error {
+	_, err := parseExecDefinition(rawDef)
+	return err
+}
+
+func parseExecDefinition(rawDef json.RawMessage) (ExecDefinition, error) {
+	var def ExecDefinition
+	err := json.Unmarshal(rawDef, &def)
+	if err != nil {
+		return ExecDefinition{}, fmt.Errorf("invalid exec input definition: %s", err)
+	}
+	if len(def.Command) == 0 {
+		return ExecDefinition{}, fmt.Errorf("exec input definition is missing a command")
+	}
+	return def, nil
+}
@@ -0,0 +1,96 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/inputs"
+)
+
+func TestExecInputRejectsDefinitionWithoutCommand(t *testing.T) {
+	// given
+	fact := inputs.NewFactory(inputs.DefaultWatchOptions(), nil)
+
+	// when
+	input, err := fact.NewInput("src", json.RawMessage(`{"kind": "exec"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(input == nil, t.Errorf, "got input %#v, want %#v", input, nil)
+}
+
+func TestExecInputStreamsStandardOutputAndError(t *testing.T) {
+	// given
+	fact := inputs.NewFactory(inputs.DefaultWatchOptions(), nil)
+	def := json.RawMessage(`{"kind": "exec", "command": ["sh", "-c", "echo out; echo err >&2"]}`)
+
+	// when
+	input, err := fact.NewInput("src", def)
+	assumeNoError(t, err)
+	defer input.Close()
+
+	// then
+	streams := fanoutStreams(t, input)
+	expectStream(t, streams, "src:stdout", "out\n")
+	expectStream(t, streams, "src:stderr", "err\n")
+	expectNoStreamsLeft(t, streams)
+}
+
+func TestExecInputReportsThatTheCommandExited(t *testing.T) {
+	// given
+	fact := inputs.NewFactory(inputs.DefaultWatchOptions(), nil)
+	def := json.RawMessage(`{"kind": "exec", "command": ["sh", "-c", "exit 3"]}`)
+	input, err := fact.NewInput("src", def)
+	assumeNoError(t, err)
+	defer input.Close()
+
+	for stream := range fanoutStreams(t, input) {
+		ioutil.ReadAll(stream.Input)
+	}
+
+	// when
+	_, err = input.Read(make([]byte, 1))
+
+	// then
+	exitErr, ok := err.(*inputs.ExitError)
+	assert.That(ok, t.Fatalf, "got error %#v, want an exit error", err)
+	assert.That(exitErr.Err != nil, t.Errorf, "exit error does not carry the exit status")
+}
+
+func TestExecInputKillsTheCommandWhenClosed(t *testing.T) {
+	// given
+	fact := inputs.NewFactory(inputs.DefaultWatchOptions(), nil)
+	def := json.RawMessage(`{"kind": "exec", "command": ["sleep", "60"]}`)
+	input, err := fact.NewInput("src", def)
+	assumeNoError(t, err)
+
+	streams := fanoutStreams(t, input)
+	ended := make(chan error)
+	go func() {
+		for stream := range streams {
+			ioutil.ReadAll(stream.Input)
+		}
+		_, err := input.Read(make([]byte, 1))
+		ended <- err
+	}()
+
+	// when
+	input.Close()
+
+	// then
+	select {
+	case err := <-ended:
+		assert.That(err != nil && err != io.EOF, t.Errorf, "got error %v, want an exit error", err)
+	case <-time.After(Timeout):
+		t.Errorf("command did not end after the input got closed")
+	}
+}
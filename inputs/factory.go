@@ -0,0 +1,106 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/szabba/munch/checkpoint"
+	"github.com/szabba/munch/sources"
+)
+
+const (
+	KindFile  = "file"
+	KindStdin = "stdin"
+	KindExec  = "exec"
+)
+
+// A Constructor builds an input of some kind out of its JSON definition. Name
+// is the name of the source the input is for.
+type Constructor func(name string, def json.RawMessage) (io.ReadCloser, error)
+
+// A Validator checks the JSON definition of an input of some kind, without
+// creating the input.
+type Validator func(name string, def json.RawMessage) error
+
+// A Factory builds inputs of the kind named in their definitions. Definitions
+// without a kind describe files.
+type Factory struct {
+	kinds map[string]kind
+}
+
+type kind struct {
+	ctor     Constructor
+	validate Validator
+}
+
+var _ sources.InputFactory = new(Factory)
+
+// NewFactory creates a factory with the file, stdin and exec kinds registered.
+// File inputs get watched and checkpointed as NewResumingFileFactory
+// describes.
+func NewFactory(watch WatchOptions, checkpoints *checkpoint.Store) *Factory {
+	files := NewResumingFileFactory(watch, checkpoints)
+	stdin := NewStdinFactory(os.Stdin)
+
+	fact := &Factory{kinds: make(map[string]kind)}
+	fact.Register(KindFile, files.NewInput, files.ValidateInput)
+	fact.Register(KindStdin, stdin.NewInput, stdin.ValidateInput)
+	fact.Register(KindExec, newExec, validateExec)
+	return fact
+}
+
+func (fact *Factory) Register(name string, ctor Constructor, validate Validator) {
+	fact.kinds[name] = kind{ctor, validate}
+}
+
+func (fact *Factory) NewInput(name string, def json.RawMessage) (io.ReadCloser, error) {
+	k, err := fact.kindOf(def)
+	if err != nil {
+		return nil, err
+	}
+	return k.ctor(name, def)
+}
+
+func (fact *Factory) ValidateInput(name string, def json.RawMessage) error {
+	k, err := fact.kindOf(def)
+	if err != nil {
+		return err
+	}
+	return k.validate(name, def)
+}
+
+func (fact *Factory) kindOf(def json.RawMessage) (kind, error) {
+	var header struct {
+		Kind string `json:"kind"`
+	}
+	err := json.Unmarshal(def, &header)
+	if err != nil {
+		return kind{}, fmt.Errorf("invalid input definition: %s", err)
+	}
+	if header.Kind == "" {
+		header.Kind = KindFile
+	}
+
+	k, ok := fact.kinds[header.Kind]
+	if !ok {
+		return kind{}, fmt.Errorf("unknown input kind %q (known kinds: %s)", header.Kind, fact.knownKinds())
+	}
+	return k, nil
+}
+
+func (fact *Factory) knownKinds() string {
+	kinds := make([]string, 0, len(fact.kinds))
+	for kind := range fact.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ", ")
+}
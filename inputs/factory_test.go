@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/inputs"
+)
+
+func TestFactoryCreatesFileInputWhenNoKindIsGiven(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\n")
+
+	fact := inputs.NewFactory(inputs.DefaultWatchOptions(), nil)
+
+	// when
+	input, err := fact.NewInput("src", fileDef(path, inputs.StartAtBeginning, false))
+	assumeNoError(t, err)
+	defer input.Close()
+	all, err := ioutil.ReadAll(input)
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(string(all) == "a\n", t.Errorf, "got %q, want %q", all, "a\n")
+}
+
+func TestFactoryRejectsUnknownKind(t *testing.T) {
+	// given
+	fact := inputs.NewFactory(inputs.DefaultWatchOptions(), nil)
+
+	// when
+	input, err := fact.NewInput("src", json.RawMessage(`{"kind": "carrier-pigeon"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(input == nil, t.Errorf, "got input %#v, want %#v", input, nil)
+}
+
+func TestFactoryRejectsSecondStdinInput(t *testing.T) {
+	// given
+	fact := inputs.NewFactory(inputs.DefaultWatchOptions(), nil)
+	first, err := fact.NewInput("first", json.RawMessage(`{"kind": "stdin"}`))
+	assumeNoError(t, err)
+	defer first.Close()
+
+	// when
+	second, err := fact.NewInput("second", json.RawMessage(`{"kind": "stdin"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(second == nil, t.Errorf, "got input %#v, want %#v", second, nil)
+}
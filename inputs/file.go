@@ -0,0 +1,205 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/checkpoint"
+	"github.com/szabba/munch/sources"
+)
+
+const (
+	StartAtBeginning = "beginning"
+	StartAtEnd       = "end"
+)
+
+// A FileDefinition describes a log file input.
+//
+// Start is either "beginning" (the default) or "end". When Follow is set the
+// input keeps reading lines appended to the file and reopens the path when the
+// file gets rotated. Otherwise it ends once it reaches the end of the file.
+//
+// Instead of a Path, a definition can have a Glob or a Dir, which reads all
+// the matching files (or all the files in the directory) as separate streams.
+// Each stream's events have the file path as their source. A followed glob
+// gets checked for new files every Rescan, which defaults to a second. Start
+// only applies to the files found when the input gets created.
+//
+// Watch and PollInterval override the factory's watch options for followed
+// files.
+type FileDefinition struct {
+	Path         string         `json:"path"`
+	Glob         string         `json:"glob"`
+	Dir          string         `json:"dir"`
+	Start        string         `json:"start"`
+	Follow       bool           `json:"follow"`
+	Rescan       munch.Duration `json:"rescan"`
+	Watch        string         `json:"watch"`
+	PollInterval munch.Duration `json:"pollInterval"`
+}
+
+type FileFactory struct {
+	watch       WatchOptions
+	checkpoints *checkpoint.Store
+}
+
+var _ sources.InputFactory = FileFactory{}
+
+func NewFileFactory() FileFactory {
+	return NewWatchingFileFactory(DefaultWatchOptions())
+}
+
+// NewWatchingFileFactory creates a factory watching followed files as
+// described by watch, unless a definition says otherwise.
+func NewWatchingFileFactory(watch WatchOptions) FileFactory {
+	return NewResumingFileFactory(watch, nil)
+}
+
+// NewResumingFileFactory creates a factory recording how far into each file
+// its inputs have read in checkpoints, and resuming from there. With nil
+// checkpoints, it works like NewWatchingFileFactory.
+func NewResumingFileFactory(watch WatchOptions, checkpoints *checkpoint.Store) FileFactory {
+	return FileFactory{watch: watch, checkpoints: checkpoints}
+}
+
+func (fact FileFactory) NewInput(_ string, rawDef json.RawMessage) (io.ReadCloser, error) {
+	in, err := fact.parse(rawDef)
+	if err != nil {
+		return nil, err
+	}
+	def, readAll, pattern, watch := in.def, in.readAll, in.pattern, in.watch
+
+	open := func(path string, readAll bool) (io.ReadCloser, error) {
+		if def.Follow {
+			return watch.follow(path, readAll), nil
+		}
+		return openFile(path, readAll)
+	}
+	if fact.checkpoints != nil {
+		plainOpen := open
+		open = func(path string, readAll bool) (io.ReadCloser, error) {
+			return resume(fact.checkpoints, path, readAll, plainOpen)
+		}
+	}
+	if pattern == "" {
+		return open(def.Path, readAll)
+	}
+
+	var rescan time.Duration
+	if def.Follow {
+		rescan = time.Duration(def.Rescan)
+		if rescan == 0 {
+			rescan = DefaultRescanInterval
+		}
+	}
+	return NewGlob(pattern, readAll, rescan, open), nil
+}
+
+func (fact FileFactory) ValidateInput(_ string, rawDef json.RawMessage) error {
+	_, err := fact.parse(rawDef)
+	return err
+}
+
+// A fileInput is a checked file input definition.
+type fileInput struct {
+	def     FileDefinition
+	readAll bool
+	pattern string
+	watch   WatchOptions
+}
+
+func (fact FileFactory) parse(rawDef json.RawMessage) (fileInput, error) {
+	var in fileInput
+	err := json.Unmarshal(rawDef, &in.def)
+	if err != nil {
+		return fileInput{}, fmt.Errorf("invalid file input definition: %s", err)
+	}
+	in.readAll, err = in.def.readAll()
+	if err != nil {
+		return fileInput{}, err
+	}
+	in.pattern, err = in.def.glob()
+	if err != nil {
+		return fileInput{}, err
+	}
+	in.watch, err = fact.watchFor(in.def)
+	if err != nil {
+		return fileInput{}, err
+	}
+	return in, nil
+}
+
+func (fact FileFactory) watchFor(def FileDefinition) (WatchOptions, error) {
+	if !def.Follow && (def.Watch != "" || def.PollInterval != 0) {
+		return WatchOptions{}, fmt.Errorf("file input watch options only apply to followed files")
+	}
+	watch := fact.watch.override(def.Watch, def.PollInterval)
+	err := watch.Validate()
+	if err != nil {
+		return WatchOptions{}, fmt.Errorf("file input: %s", err)
+	}
+	return watch, nil
+}
+
+func (def FileDefinition) glob() (string, error) {
+	set := 0
+	for _, s := range []string{def.Path, def.Glob, def.Dir} {
+		if s != "" {
+			set++
+		}
+	}
+	switch {
+	case set == 0:
+		return "", fmt.Errorf("file input definition is missing a path, glob or dir")
+	case set > 1:
+		return "", fmt.Errorf("file input definition must have only one of a path, glob or dir")
+	case def.Rescan < 0:
+		return "", fmt.Errorf("file input rescan interval cannot be negative")
+	case def.Dir != "":
+		return filepath.Join(def.Dir, "*"), nil
+	}
+
+	_, err := filepath.Match(def.Glob, "")
+	if err != nil {
+		return "", fmt.Errorf("invalid file input glob %q: %s", def.Glob, err)
+	}
+	return def.Glob, nil
+}
+
+func (def FileDefinition) readAll() (bool, error) {
+	switch def.Start {
+	case "", StartAtBeginning:
+		return true, nil
+	case StartAtEnd:
+		return false, nil
+	default:
+		return false, fmt.Errorf(
+			"file input start must be %q or %q, got %q",
+			StartAtBeginning, StartAtEnd, def.Start)
+	}
+}
+
+func openFile(path string, readAll bool) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if readAll {
+		return f, nil
+	}
+	_, err = f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
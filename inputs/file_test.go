@@ -0,0 +1,202 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/inputs"
+)
+
+const Timeout = 5 * time.Second
+
+func TestFileFactoryRejectsInvalidDefinition(t *testing.T) {
+	// given
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", json.RawMessage(`[]`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(input == nil, t.Errorf, "got input %#v, want %#v", input, nil)
+}
+
+func TestFileFactoryRejectsDefinitionWithoutPath(t *testing.T) {
+	// given
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", json.RawMessage(`{}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(input == nil, t.Errorf, "got input %#v, want %#v", input, nil)
+}
+
+func TestFileFactoryRejectsUnknownStart(t *testing.T) {
+	// given
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", json.RawMessage(`{"path": "x.log", "start": "middle"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(input == nil, t.Errorf, "got input %#v, want %#v", input, nil)
+}
+
+func TestFileInputReadsWholeFileWhenNotFollowing(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\nb\n")
+
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", fileDef(path, inputs.StartAtBeginning, false))
+	assumeNoError(t, err)
+	defer input.Close()
+	all, err := ioutil.ReadAll(input)
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(string(all) == "a\nb\n", t.Errorf, "got %q, want %q", all, "a\nb\n")
+}
+
+func TestFileInputSkipsExistingContentWhenStartingAtTheEnd(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\nb\n")
+
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", fileDef(path, inputs.StartAtEnd, false))
+	assumeNoError(t, err)
+	defer input.Close()
+	all, err := ioutil.ReadAll(input)
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(string(all) == "", t.Errorf, "got %q, want %q", all, "")
+}
+
+func TestFileInputFollowsAppendedLines(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\n")
+
+	fact := inputs.NewFileFactory()
+	input, err := fact.NewInput("src", fileDef(path, inputs.StartAtBeginning, true))
+	assumeNoError(t, err)
+	defer input.Close()
+	lines := readLines(input)
+
+	// when
+	appendFile(t, path, "b\n")
+
+	// then
+	expectLine(t, lines, "a")
+	expectLine(t, lines, "b")
+}
+
+func TestFileInputEndsWhenClosedWhileFollowing(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "")
+
+	fact := inputs.NewFileFactory()
+	input, err := fact.NewInput("src", fileDef(path, inputs.StartAtBeginning, true))
+	assumeNoError(t, err)
+	lines := readLines(input)
+
+	// when
+	input.Close()
+
+	// then
+	select {
+	case line, ok := <-lines:
+		assert.That(!ok, t.Errorf, "got unexpected line %q", line)
+	case <-time.After(Timeout):
+		t.Errorf("input did not end after being closed")
+	}
+}
+
+func fileDef(path, start string, follow bool) json.RawMessage {
+	def, _ := json.Marshal(inputs.FileDefinition{Path: path, Start: start, Follow: follow})
+	return def
+}
+
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+func expectLine(t *testing.T, lines <-chan string, want string) {
+	t.Helper()
+	select {
+	case got, ok := <-lines:
+		assert.That(ok, t.Fatalf, "input ended, want line %q", want)
+		assert.That(got == want, t.Errorf, "got line %q, want %q", got, want)
+	case <-time.After(Timeout):
+		t.Fatalf("timed out waiting for line %q", want)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "munch-inputs")
+	assumeNoError(t, err)
+	return dir
+}
+
+func writeFile(t *testing.T, dir, content string) string {
+	t.Helper()
+	return writeNamedFile(t, dir, "test.log", content)
+}
+
+func writeNamedFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	assumeNoError(t, err)
+	return path
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assumeNoError(t, err)
+	defer f.Close()
+	_, err = fmt.Fprint(f, content)
+	assumeNoError(t, err)
+}
+
+func assumeNoError(t *testing.T, err error) {
+	t.Helper()
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+}
@@ -0,0 +1,119 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/szabba/munch/sources"
+)
+
+// DefaultRescanInterval is how often a followed glob looks for new files.
+const DefaultRescanInterval = time.Second
+
+// A Glob is an input reading every file matching a pattern as a separate
+// stream named after the file path. When following, files that start matching
+// later get picked up and read from their beginning.
+type Glob struct {
+	once    sync.Once
+	pattern string
+	open    func(path string, readAll bool) (io.ReadCloser, error)
+	streams chan sources.Stream
+	stop    chan struct{}
+	done    chan struct{}
+	err     error
+}
+
+var _ sources.Fanout = new(Glob)
+
+// NewGlob starts looking for files matching pattern. Files are opened with
+// open, which gets told whether to read a file from its beginning. When
+// rescan is positive, the glob gets re-evaluated that often until closed.
+func NewGlob(pattern string, readAll bool, rescan time.Duration, open func(path string, readAll bool) (io.ReadCloser, error)) *Glob {
+	g := &Glob{
+		pattern: pattern,
+		open:    open,
+		streams: make(chan sources.Stream),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go g.run(readAll, rescan)
+	return g
+}
+
+func (g *Glob) Streams() <-chan sources.Stream {
+	return g.streams
+}
+
+func (g *Glob) Read(p []byte) (int, error) {
+	<-g.done
+	if g.err != nil {
+		return 0, g.err
+	}
+	return 0, io.EOF
+}
+
+func (g *Glob) Close() error {
+	g.once.Do(func() { close(g.stop) })
+	return nil
+}
+
+func (g *Glob) run(readAll bool, rescan time.Duration) {
+	defer close(g.done)
+	defer close(g.streams)
+
+	seen := make(map[string]bool)
+	g.err = g.scan(seen, readAll)
+	if g.err != nil || rescan <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(rescan)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+		g.err = g.scan(seen, true)
+		if g.err != nil {
+			return
+		}
+	}
+}
+
+func (g *Glob) scan(seen map[string]bool, readAll bool) error {
+	paths, err := filepath.Glob(g.pattern)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		seen[path] = true
+
+		input, err := g.open(path, readAll)
+		if err != nil {
+			return err
+		}
+		select {
+		case g.streams <- sources.Stream{Name: path, Input: input}:
+		case <-g.stop:
+			input.Close()
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/inputs"
+	"github.com/szabba/munch/sources"
+)
+
+func TestFileFactoryRejectsDefinitionWithPathAndGlob(t *testing.T) {
+	// given
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", json.RawMessage(`{"path": "x.log", "glob": "*.log"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(input == nil, t.Errorf, "got input %#v, want %#v", input, nil)
+}
+
+func TestFileFactoryRejectsMalformedGlob(t *testing.T) {
+	// given
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", json.RawMessage(`{"glob": "[.log"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(input == nil, t.Errorf, "got input %#v, want %#v", input, nil)
+}
+
+func TestGlobInputReadsEveryMatchingFileAsAStream(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a := writeNamedFile(t, dir, "a.log", "from a\n")
+	b := writeNamedFile(t, dir, "b.log", "from b\n")
+	writeNamedFile(t, dir, "c.txt", "from c\n")
+
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", globDef(filepath.Join(dir, "*.log"), false))
+	assumeNoError(t, err)
+	defer input.Close()
+
+	// then
+	streams := fanoutStreams(t, input)
+	expectStream(t, streams, a, "from a\n")
+	expectStream(t, streams, b, "from b\n")
+	expectNoStreamsLeft(t, streams)
+}
+
+func TestFollowedGlobInputPicksUpNewFiles(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a := writeNamedFile(t, dir, "a.log", "from a\n")
+
+	fact := inputs.NewFileFactory()
+	input, err := fact.NewInput("src", globDef(filepath.Join(dir, "*.log"), true))
+	assumeNoError(t, err)
+	defer input.Close()
+	streams := fanoutStreams(t, input)
+
+	first := nextStream(t, streams)
+	defer first.Input.Close()
+	assert.That(first.Name == a, t.Fatalf, "got stream %q, want %q", first.Name, a)
+
+	// when
+	b := writeNamedFile(t, dir, "b.log", "from b\n")
+
+	// then
+	second := nextStream(t, streams)
+	defer second.Input.Close()
+	assert.That(second.Name == b, t.Fatalf, "got stream %q, want %q", second.Name, b)
+	expectLine(t, readLines(second.Input), "from b")
+}
+
+func TestDirInputReadsEveryFileInTheDirectory(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	a := writeNamedFile(t, dir, "a.log", "from a\n")
+	err := os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	assumeNoError(t, err)
+
+	fact := inputs.NewFileFactory()
+	def, _ := json.Marshal(inputs.FileDefinition{Dir: dir})
+
+	// when
+	input, err := fact.NewInput("src", def)
+	assumeNoError(t, err)
+	defer input.Close()
+
+	// then
+	streams := fanoutStreams(t, input)
+	expectStream(t, streams, a, "from a\n")
+	expectNoStreamsLeft(t, streams)
+}
+
+func globDef(pattern string, follow bool) json.RawMessage {
+	def, _ := json.Marshal(inputs.FileDefinition{
+		Glob:   pattern,
+		Follow: follow,
+		Rescan: munch.Duration(10 * time.Millisecond),
+	})
+	return def
+}
+
+func fanoutStreams(t *testing.T, input interface{}) <-chan sources.Stream {
+	t.Helper()
+	fanout, ok := input.(sources.Fanout)
+	assert.That(ok, t.Fatalf, "input %#v is not a fanout", input)
+	return fanout.Streams()
+}
+
+func nextStream(t *testing.T, streams <-chan sources.Stream) sources.Stream {
+	t.Helper()
+	select {
+	case stream, ok := <-streams:
+		assert.That(ok, t.Fatalf, "input ended, wanted another stream")
+		return stream
+	case <-time.After(Timeout):
+		t.Fatalf("timed out waiting for a stream")
+		return sources.Stream{}
+	}
+}
+
+func expectStream(t *testing.T, streams <-chan sources.Stream, name, content string) {
+	t.Helper()
+	stream := nextStream(t, streams)
+	defer stream.Input.Close()
+	assert.That(stream.Name == name, t.Errorf, "got stream %q, want %q", stream.Name, name)
+	all, err := ioutil.ReadAll(stream.Input)
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(string(all) == content, t.Errorf, "got %q from stream %q, want %q", all, name, content)
+}
+
+func expectNoStreamsLeft(t *testing.T, streams <-chan sources.Stream) {
+	t.Helper()
+	select {
+	case stream, ok := <-streams:
+		assert.That(!ok, t.Errorf, "got unexpected stream %q", stream.Name)
+	case <-time.After(Timeout):
+		t.Errorf("input did not end after all the files were found")
+	}
+}
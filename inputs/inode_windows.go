@@ -0,0 +1,14 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs
+
+import (
+	"os"
+)
+
+// inode is always zero on Windows, so only truncation can be detected there.
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
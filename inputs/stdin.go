@@ -0,0 +1,154 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/szabba/munch/sources"
+)
+
+// A StdinFactory creates inputs reading the standard input of the process.
+// As there is only one, a StdinFactory refuses to create an input for a
+// second source while the first one has its input open. The source that has
+// it can get a new one when restarted.
+//
+// The standard input is read by a single goroutine for the whole process, as
+// a read from it cannot be interrupted. Each new input picks up where the one
+// before it stopped.
+type StdinFactory struct {
+	once  sync.Once
+	r     io.Reader
+	lines chan string
+	ended chan struct{}
+	err   error
+
+	lock  sync.Mutex
+	taken string
+	owner *stdinInput
+}
+
+var _ sources.InputFactory = new(StdinFactory)
+
+// NewStdinFactory creates a factory of inputs reading lines from r, which is
+// meant to be the standard input.
+func NewStdinFactory(r io.Reader) *StdinFactory {
+	return &StdinFactory{
+		r:     r,
+		lines: make(chan string),
+		ended: make(chan struct{}),
+	}
+}
+
+// NewInput hands the standard input to the source. An input the source got
+// before gets closed, so that it reads no more lines.
+func (fact *StdinFactory) NewInput(name string, _ json.RawMessage) (io.ReadCloser, error) {
+	fact.lock.Lock()
+	err := fact.check(name)
+	if err != nil {
+		fact.lock.Unlock()
+		return nil, err
+	}
+	fact.once.Do(func() { go fact.readLines() })
+	old := fact.owner
+	fact.taken = name
+	fact.owner = &stdinInput{fact: fact, closed: make(chan struct{})}
+	input := fact.owner
+	fact.lock.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	return input, nil
+}
+
+func (fact *StdinFactory) ValidateInput(name string, _ json.RawMessage) error {
+	fact.lock.Lock()
+	defer fact.lock.Unlock()
+	return fact.check(name)
+}
+
+func (fact *StdinFactory) check(name string) error {
+	if fact.taken != "" && fact.taken != name {
+		return fmt.Errorf("standard input is already read by source %q", fact.taken)
+	}
+	return nil
+}
+
+func (fact *StdinFactory) release(input *stdinInput) {
+	fact.lock.Lock()
+	defer fact.lock.Unlock()
+	if fact.owner == input {
+		fact.owner = nil
+		fact.taken = ""
+	}
+}
+
+func (fact *StdinFactory) readLines() {
+	defer close(fact.ended)
+	reader := bufio.NewReader(fact.r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			fact.lines <- strings.TrimRight(line, "\r\n")
+		}
+		if err != nil {
+			fact.err = err
+			return
+		}
+	}
+}
+
+// A stdinInput reads the lines of the standard input until closed. Closing it
+// lets another source take the standard input.
+type stdinInput struct {
+	once   sync.Once
+	fact   *StdinFactory
+	closed chan struct{}
+	buf    []byte
+}
+
+func (in *stdinInput) Read(p []byte) (int, error) {
+	if len(in.buf) == 0 {
+		err := in.nextLine()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, in.buf)
+	in.buf = in.buf[n:]
+	return n, nil
+}
+
+func (in *stdinInput) nextLine() error {
+	select {
+	case <-in.closed:
+		return io.EOF
+	default:
+	}
+	select {
+	case line := <-in.fact.lines:
+		in.buf = append(in.buf[:0], line...)
+		in.buf = append(in.buf, '\n')
+		return nil
+	case <-in.fact.ended:
+		return in.fact.err
+	case <-in.closed:
+		return io.EOF
+	}
+}
+
+func (in *stdinInput) Close() error {
+	in.once.Do(func() {
+		close(in.closed)
+		in.fact.release(in)
+	})
+	return nil
+}
@@ -0,0 +1,99 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/inputs"
+)
+
+var StdinDef = json.RawMessage(`{"kind": "stdin"}`)
+
+func TestStdinInputOfRestartedSourceGetsTheFollowingLines(t *testing.T) {
+	// given
+	r, w := io.Pipe()
+	defer w.Close()
+	fact := inputs.NewStdinFactory(r)
+
+	first, err := fact.NewInput("app", StdinDef)
+	assumeNoError(t, err)
+	firstLines := readLines(first)
+	io.WriteString(w, "a\n")
+	expectLine(t, firstLines, "a")
+
+	// when
+	second, err := fact.NewInput("app", StdinDef)
+	assumeNoError(t, err)
+	defer second.Close()
+	secondLines := readLines(second)
+	io.WriteString(w, "b\nc\n")
+
+	// then
+	expectLine(t, secondLines, "b")
+	expectLine(t, secondLines, "c")
+	expectEnd(t, firstLines)
+}
+
+func TestStdinCanBeTakenByAnotherSourceOnceClosed(t *testing.T) {
+	// given
+	r, w := io.Pipe()
+	defer w.Close()
+	fact := inputs.NewStdinFactory(r)
+
+	first, err := fact.NewInput("app", StdinDef)
+	assumeNoError(t, err)
+
+	// when
+	first.Close()
+	second, err := fact.NewInput("other", StdinDef)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	defer second.Close()
+	secondLines := readLines(second)
+	io.WriteString(w, "a\n")
+	expectLine(t, secondLines, "a")
+}
+
+func TestStdinInputCreatedAfterTheEndEndsRightAway(t *testing.T) {
+	// given
+	r, w := io.Pipe()
+	fact := inputs.NewStdinFactory(r)
+
+	first, err := fact.NewInput("app", StdinDef)
+	assumeNoError(t, err)
+	io.WriteString(w, "a\n")
+	w.Close()
+	all, err := ioutil.ReadAll(first)
+	assumeNoError(t, err)
+	assert.That(string(all) == "a\n", t.Fatalf, "got %q, want %q", all, "a\n")
+
+	// when
+	second, err := fact.NewInput("app", StdinDef)
+	assumeNoError(t, err)
+	defer second.Close()
+	all, err = ioutil.ReadAll(second)
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(len(all) == 0, t.Errorf, "got %q, want nothing", all)
+}
+
+func expectEnd(t *testing.T, lines <-chan string) {
+	t.Helper()
+	select {
+	case line, ok := <-lines:
+		assert.That(!ok, t.Errorf, "got line %q, want the input to end", line)
+	case <-time.After(Timeout):
+		t.Fatalf("timed out waiting for the input to end")
+	}
+}
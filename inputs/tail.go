@@ -0,0 +1,66 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs
+
+import (
+	"io"
+	"sync"
+
+	"github.com/fstab/grok_exporter/tailer"
+)
+
+// A TailReader turns the lines coming out of a tailer back into a stream of
+// newline-terminated bytes.
+type TailReader struct {
+	once   sync.Once
+	tail   tailer.Tailer
+	closed chan struct{}
+	buf    []byte
+}
+
+var _ io.ReadCloser = new(TailReader)
+
+func NewTailReader(tail tailer.Tailer) *TailReader {
+	return &TailReader{tail: tail, closed: make(chan struct{})}
+}
+
+func (r *TailReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		err := r.nextLine()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *TailReader) Close() error {
+	r.once.Do(func() {
+		close(r.closed)
+		r.tail.Close()
+	})
+	return nil
+}
+
+func (r *TailReader) nextLine() error {
+	select {
+	case line, ok := <-r.tail.Lines():
+		if !ok {
+			return io.EOF
+		}
+		r.buf = append(r.buf[:0], line...)
+		r.buf = append(r.buf, '\n')
+		return nil
+	case err, ok := <-r.tail.Errors():
+		if !ok {
+			return io.EOF
+		}
+		return err
+	case <-r.closed:
+		return io.EOF
+	}
+}
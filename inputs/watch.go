@@ -0,0 +1,74 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fstab/grok_exporter/tailer"
+
+	"github.com/szabba/munch"
+)
+
+const (
+	// WatchEvents notices file changes through filesystem notifications, like
+	// inotify on Linux.
+	WatchEvents = "events"
+	// WatchPoll checks followed files for changes periodically. Unlike
+	// notifications, this works on network filesystems and bind mounts. A
+	// file that gets moved away must be recreated before the next poll, or the
+	// input fails.
+	WatchPoll = "poll"
+)
+
+// WatchOptions say how followed files get watched for changes.
+type WatchOptions struct {
+	Mode         string         `json:"mode"`
+	PollInterval munch.Duration `json:"pollInterval"`
+}
+
+func DefaultWatchOptions() WatchOptions {
+	return WatchOptions{
+		Mode:         WatchEvents,
+		PollInterval: munch.Duration(time.Second),
+	}
+}
+
+func (opts WatchOptions) Validate() error {
+	switch opts.Mode {
+	case WatchEvents, WatchPoll:
+	default:
+		return fmt.Errorf("watch mode must be %q or %q, got %q", WatchEvents, WatchPoll, opts.Mode)
+	}
+	if opts.PollInterval <= 0 {
+		return fmt.Errorf("poll interval must be positive, got %s", time.Duration(opts.PollInterval))
+	}
+	return nil
+}
+
+// override returns opts with the mode and interval replaced by the non-zero
+// values passed in.
+func (opts WatchOptions) override(mode string, interval munch.Duration) WatchOptions {
+	if mode != "" {
+		opts.Mode = mode
+	}
+	if interval != 0 {
+		opts.PollInterval = interval
+	}
+	return opts
+}
+
+func (opts WatchOptions) follow(path string, readAll bool) io.ReadCloser {
+	const failOnMissing = false
+	var tail tailer.Tailer
+	if opts.Mode == WatchPoll {
+		tail = tailer.RunPollingFileTailer(path, readAll, failOnMissing, time.Duration(opts.PollInterval), nil)
+	} else {
+		tail = tailer.RunFseventFileTailer(path, readAll, failOnMissing, nil)
+	}
+	return NewTailReader(tail)
+}
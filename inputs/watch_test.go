@@ -0,0 +1,129 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package inputs_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/inputs"
+)
+
+var WatchModes = []string{inputs.WatchEvents, inputs.WatchPoll}
+
+func TestFileFactoryRejectsUnknownWatchMode(t *testing.T) {
+	// given
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", json.RawMessage(`{"path": "x.log", "follow": true, "watch": "psychic"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(input == nil, t.Errorf, "got input %#v, want %#v", input, nil)
+}
+
+func TestFileFactoryRejectsWatchModeForFileThatIsNotFollowed(t *testing.T) {
+	// given
+	fact := inputs.NewFileFactory()
+
+	// when
+	input, err := fact.NewInput("src", json.RawMessage(`{"path": "x.log", "watch": "poll"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(input == nil, t.Errorf, "got input %#v, want %#v", input, nil)
+}
+
+func TestWatchedFileInputFollowsAppendedLines(t *testing.T) {
+	for _, mode := range WatchModes {
+		t.Run(mode, func(t *testing.T) {
+			// given
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			path := writeFile(t, dir, "a\n")
+
+			fact := inputs.NewFileFactory()
+			input, err := fact.NewInput("src", watchDef(path, mode))
+			assumeNoError(t, err)
+			defer input.Close()
+			lines := readLines(input)
+			expectLine(t, lines, "a")
+
+			// when
+			appendFile(t, path, "b\n")
+			appendFile(t, path, "c\n")
+
+			// then
+			expectLine(t, lines, "b")
+			expectLine(t, lines, "c")
+		})
+	}
+}
+
+func TestWatchedFileInputFollowsTruncatedFile(t *testing.T) {
+	for _, mode := range WatchModes {
+		t.Run(mode, func(t *testing.T) {
+			// given
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			path := writeFile(t, dir, "a\n")
+
+			fact := inputs.NewFileFactory()
+			input, err := fact.NewInput("src", watchDef(path, mode))
+			assumeNoError(t, err)
+			defer input.Close()
+			lines := readLines(input)
+			expectLine(t, lines, "a")
+
+			// when
+			err = os.Truncate(path, 0)
+			assumeNoError(t, err)
+			time.Sleep(100 * time.Millisecond)
+			appendFile(t, path, "b\n")
+
+			// then
+			expectLine(t, lines, "b")
+		})
+	}
+}
+
+func TestFileFactoryWatchesWithItsDefaultMode(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "a\n")
+
+	fact := inputs.NewWatchingFileFactory(inputs.WatchOptions{
+		Mode:         inputs.WatchPoll,
+		PollInterval: munch.Duration(10 * time.Millisecond),
+	})
+	input, err := fact.NewInput("src", fileDef(path, inputs.StartAtBeginning, true))
+	assumeNoError(t, err)
+	defer input.Close()
+	lines := readLines(input)
+
+	// when
+	appendFile(t, path, "b\n")
+
+	// then
+	expectLine(t, lines, "a")
+	expectLine(t, lines, "b")
+}
+
+func watchDef(path, mode string) json.RawMessage {
+	def, _ := json.Marshal(inputs.FileDefinition{
+		Path:         path,
+		Follow:       true,
+		Watch:        mode,
+		PollInterval: munch.Duration(10 * time.Millisecond),
+	})
+	return def
+}
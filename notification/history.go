@@ -0,0 +1,114 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package notification
+
+import (
+	"sort"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+// A HistoryLimit bounds the recent events kept for replay to new clients.
+// Events is the most kept per source, and Age how long ago an event can have
+// been broadcast to still get replayed. A zero Age does not limit anything.
+type HistoryLimit struct {
+	Events int
+	Age    time.Duration
+}
+
+type history struct {
+	limit   HistoryLimit
+	clock   func() time.Time
+	seq     uint64
+	sources map[string]*ring
+}
+
+type entry struct {
+	seq uint64
+	at  time.Time
+	evt munch.Event
+}
+
+func newHistory(limit HistoryLimit, clock func() time.Time) *history {
+	return &history{
+		limit:   limit,
+		clock:   clock,
+		sources: make(map[string]*ring),
+	}
+}
+
+func (h *history) add(evt munch.Event) {
+	if h.limit.Events <= 0 {
+		return
+	}
+	r := h.sources[evt.Source]
+	if r == nil {
+		r = newRing(h.limit.Events)
+		h.sources[evt.Source] = r
+	}
+	h.seq++
+	r.push(entry{seq: h.seq, at: h.clock(), evt: evt})
+}
+
+// recent returns the events kept from all sources, in the order they were
+// broadcast in.
+func (h *history) recent() []munch.Event {
+	var cutoff time.Time
+	if h.limit.Age > 0 {
+		cutoff = h.clock().Add(-h.limit.Age)
+	}
+
+	var entries []entry
+	for _, r := range h.sources {
+		r.dropBefore(cutoff)
+		entries = r.appendTo(entries)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+
+	evts := make([]munch.Event, len(entries))
+	for i, e := range entries {
+		evts[i] = e.evt
+	}
+	return evts
+}
+
+type ring struct {
+	entries []entry
+	start   int
+	n       int
+}
+
+func newRing(size int) *ring {
+	return &ring{entries: make([]entry, size)}
+}
+
+func (r *ring) push(e entry) {
+	if len(r.entries) == 0 {
+		return
+	}
+	end := (r.start + r.n) % len(r.entries)
+	r.entries[end] = e
+	if r.n < len(r.entries) {
+		r.n++
+	} else {
+		r.start = (r.start + 1) % len(r.entries)
+	}
+}
+
+func (r *ring) dropBefore(cutoff time.Time) {
+	for r.n > 0 && r.entries[r.start].at.Before(cutoff) {
+		r.entries[r.start] = entry{}
+		r.start = (r.start + 1) % len(r.entries)
+		r.n--
+	}
+}
+
+func (r *ring) appendTo(entries []entry) []entry {
+	for i := 0; i < r.n; i++ {
+		entries = append(entries, r.entries[(r.start+i)%len(r.entries)])
+	}
+	return entries
+}
@@ -0,0 +1,168 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package notification_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/notification"
+)
+
+func TestServiceReplaysRecentEventsOfEachSourceToNewClient(t *testing.T) {
+	// given
+	clock := NewTestClock()
+	service := notification.NewServiceWith(historyOptions(notification.HistoryLimit{Events: 2}, clock))
+	defer service.Close()
+
+	service.Broadcast(munch.Event{Source: "a", Message: "a1"})
+	service.Broadcast(munch.Event{Source: "b", Message: "b1"})
+	service.Broadcast(munch.Event{Source: "a", Message: "a2"})
+	service.Broadcast(munch.Event{Source: "a", Message: "a3"})
+	service.Broadcast("not an event")
+
+	sender := new(SliceSender)
+
+	// when
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+
+	// then
+	sender.AssertGotMessages(t, "b1", "a2", "a3")
+}
+
+func TestServiceDoesNotReplayEventsToLiveSubscriber(t *testing.T) {
+	// given
+	clock := NewTestClock()
+	service := notification.NewServiceWith(historyOptions(notification.HistoryLimit{Events: 2}, clock))
+	defer service.Close()
+
+	service.Broadcast(munch.Event{Source: "a", Message: "old"})
+	sender := new(SliceSender)
+
+	// when
+	service.SubscribeLive(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+	service.Broadcast(munch.Event{Source: "a", Message: "new"})
+
+	// then
+	sender.AssertGotMessages(t, "new")
+}
+
+func TestServiceDoesNotReplayEventsOlderThanTheLimit(t *testing.T) {
+	// given
+	clock := NewTestClock()
+	limit := notification.HistoryLimit{Events: 10, Age: time.Minute}
+	service := notification.NewServiceWith(historyOptions(limit, clock))
+	defer service.Close()
+
+	service.Broadcast(munch.Event{Source: "a", Message: "old"})
+	clock.Advance(2 * time.Minute)
+	service.Broadcast(munch.Event{Source: "a", Message: "new"})
+
+	sender := new(SliceSender)
+
+	// when
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+
+	// then
+	sender.AssertGotMessages(t, "new")
+}
+
+func TestServiceSendsLiveEventsAfterReplayedOnesWithoutRepeats(t *testing.T) {
+	// given
+	clock := NewTestClock()
+	service := notification.NewServiceWith(historyOptions(notification.HistoryLimit{Events: 10}, clock))
+	defer service.Close()
+
+	service.Broadcast(munch.Event{Source: "a", Message: "before"})
+
+	sender := new(SliceSender)
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+
+	// when
+	service.Broadcast(munch.Event{Source: "a", Message: "after"})
+
+	// then
+	sender.AssertGotMessages(t, "before", "after")
+}
+
+func TestServiceWithoutHistoryReplaysNothing(t *testing.T) {
+	// given
+	service := notification.NewService()
+	defer service.Close()
+
+	service.Broadcast(munch.Event{Source: "a", Message: "before"})
+
+	sender := new(SliceSender)
+
+	// when
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+
+	// then
+	sender.AssertGotMessages(t)
+}
+
+func historyOptions(limit notification.HistoryLimit, clock *TestClock) notification.Options {
+	opts := notification.DefaultOptions()
+	opts.History = limit
+	opts.Clock = clock.Now
+	return opts
+}
+
+type TestClock struct {
+	now time.Time
+}
+
+func NewTestClock() *TestClock {
+	return &TestClock{now: time.Unix(0, 0)}
+}
+
+func (c *TestClock) Now() time.Time { return c.now }
+
+func (c *TestClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+type SliceSender struct {
+	lock sync.Mutex
+	msgs []interface{}
+}
+
+func (s *SliceSender) Send(msg interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.msgs = append(s.msgs, msg)
+}
+
+func (s *SliceSender) Messages() []interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]interface{}(nil), s.msgs...)
+}
+
+// AssertGotMessages checks that exactly the events with the wanted messages
+// were sent, waiting for them to arrive first.
+func (s *SliceSender) AssertGotMessages(t *testing.T, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(Timeout)
+	for len(s.Messages()) < len(want) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(SleepTime)
+
+	msgs := s.Messages()
+	assert.That(len(msgs) == len(want), t.Fatalf, "got %d messages, want %d: %#v", len(msgs), len(want), msgs)
+	for i, msg := range msgs {
+		evt, ok := msg.(munch.Event)
+		assert.That(ok, t.Errorf, "message %d is a %T, want a %T", i, msg, evt)
+		assert.That(evt.Message == want[i], t.Errorf, "got message %d = %q, want %q", i, evt.Message, want[i])
+	}
+}
@@ -0,0 +1,169 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package notification
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// DropOldest makes room for a new message in a full queue by dropping the
+	// oldest one queued.
+	DropOldest = "drop-oldest"
+	// DropNewest drops new messages while the queue is full.
+	DropNewest = "drop-newest"
+	// Disconnect unsubscribes a client whose queue overflows.
+	Disconnect = "disconnect"
+)
+
+// QueueOptions describe the queue of messages waiting to be sent to each
+// client, and what happens when a client is too slow to keep up with it.
+type QueueOptions struct {
+	Size     int
+	Overflow string
+}
+
+func DefaultQueueOptions() QueueOptions {
+	return QueueOptions{Size: 256, Overflow: DropOldest}
+}
+
+func (opts QueueOptions) Validate() error {
+	if opts.Size <= 0 {
+		return fmt.Errorf("queue size must be positive, got %d", opts.Size)
+	}
+	switch opts.Overflow {
+	case DropOldest, DropNewest, Disconnect:
+		return nil
+	default:
+		return fmt.Errorf(
+			"queue overflow policy must be %q, %q or %q, got %q",
+			DropOldest, DropNewest, Disconnect, opts.Overflow)
+	}
+}
+
+// A queue holds the messages waiting to be sent to a client. A goroutine of
+// its own sends them, so that a slow client holds up no one else.
+type queue struct {
+	lock    sync.Mutex
+	cond    *sync.Cond
+	opts    QueueOptions
+	sender  sender
+	msgs    []queued
+	dropped uint64
+	closed  bool
+}
+
+// A queued message is kept when it must not be dropped, whatever the overflow
+// policy.
+type queued struct {
+	msg  interface{}
+	keep bool
+}
+
+func newQueue(opts QueueOptions, sndr sender) *queue {
+	q := &queue{opts: opts, sender: sndr}
+	q.cond = sync.NewCond(&q.lock)
+	go q.run()
+	return q
+}
+
+// push adds a message to the queue. It reports false when the queue is full
+// and the overflow policy is to disconnect.
+func (q *queue) push(msg interface{}) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return true
+	}
+	if len(q.msgs) >= q.opts.Size {
+		q.dropped++
+		switch q.opts.Overflow {
+		case DropNewest:
+			return true
+		case Disconnect:
+			return false
+		}
+		if !q.dropOldest() {
+			return true
+		}
+	}
+	q.pushUnbounded(msg)
+	return true
+}
+
+// dropOldest drops the oldest message that is not kept, reporting false when
+// all of them are. The caller must hold the lock.
+func (q *queue) dropOldest() bool {
+	for i, m := range q.msgs {
+		if !m.keep {
+			copy(q.msgs[i:], q.msgs[i+1:])
+			q.msgs[len(q.msgs)-1] = queued{}
+			q.msgs = q.msgs[:len(q.msgs)-1]
+			return true
+		}
+	}
+	return false
+}
+
+// pushUnbounded adds a message to the queue even if it is full. The caller
+// must hold the lock.
+func (q *queue) pushUnbounded(msg interface{}) {
+	q.msgs = append(q.msgs, queued{msg: msg})
+	q.cond.Signal()
+}
+
+// pushKept adds a message to the queue even if it is full, and makes sure it
+// does not get dropped later.
+func (q *queue) pushKept(msg interface{}) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.closed {
+		return
+	}
+	q.msgs = append(q.msgs, queued{msg: msg, keep: true})
+	q.cond.Signal()
+}
+
+func (q *queue) stats() (queued int, dropped uint64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.msgs), q.dropped
+}
+
+// close makes the sending goroutine stop, dropping any messages still queued.
+func (q *queue) close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	q.msgs = nil
+	q.cond.Signal()
+}
+
+func (q *queue) run() {
+	for {
+		msg, ok := q.pop()
+		if !ok {
+			return
+		}
+		q.sender.send(msg)
+	}
+}
+
+func (q *queue) pop() (interface{}, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for len(q.msgs) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	msg := q.msgs[0].msg
+	q.msgs[0] = queued{}
+	q.msgs = q.msgs[1:]
+	return msg, true
+}
@@ -0,0 +1,258 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package notification_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/notification"
+)
+
+func TestServiceDoesNotLetASlowClientHoldUpOthers(t *testing.T) {
+	// given
+	service := notification.NewService()
+	defer service.Close()
+
+	idGenerator := new(munch.ClientIDGenerator)
+	slowID, fastID := idGenerator.NextID(), idGenerator.NextID()
+	slow := NewStalledSender()
+	defer slow.Release()
+	fast := NewTestSender(t)
+
+	service.Subscribe(slowID, slow.Send, nil)
+	defer service.Unsubscribe(slowID)
+	service.Subscribe(fastID, fast.Send, nil)
+	defer service.Unsubscribe(fastID)
+
+	// when
+	service.Broadcast(Message)
+	slow.WaitStalled(t)
+	service.Broadcast(Message)
+
+	// then
+	fast.AssertGotString(Message)
+	fast.AssertGotString(Message)
+}
+
+func TestServiceDropsOldestMessagesOfASlowClient(t *testing.T) {
+	// given
+	service := notification.NewServiceWith(queueOptions(2, notification.DropOldest))
+	defer service.Close()
+
+	sender := NewStalledSender()
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+
+	service.Broadcast(event(0))
+	sender.WaitStalled(t)
+
+	// when
+	for i := 1; i <= 4; i++ {
+		service.Broadcast(event(i))
+	}
+	sender.Release()
+
+	// then
+	sender.AssertGotMessages(t, "0", "3", "4")
+	assertDropped(t, service, 2)
+}
+
+func TestServiceDropsNewestMessagesOfASlowClient(t *testing.T) {
+	// given
+	service := notification.NewServiceWith(queueOptions(2, notification.DropNewest))
+	defer service.Close()
+
+	sender := NewStalledSender()
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+
+	service.Broadcast(event(0))
+	sender.WaitStalled(t)
+
+	// when
+	for i := 1; i <= 4; i++ {
+		service.Broadcast(event(i))
+	}
+	sender.Release()
+
+	// then
+	sender.AssertGotMessages(t, "0", "1", "2")
+	assertDropped(t, service, 2)
+}
+
+func TestServiceDisconnectsASlowClient(t *testing.T) {
+	// given
+	service := notification.NewServiceWith(queueOptions(2, notification.Disconnect))
+	defer service.Close()
+
+	sender := NewStalledSender()
+	defer sender.Release()
+	disconnected := make(chan error, 1)
+	service.Subscribe(ClientID, sender.Send, func(reason error) { disconnected <- reason })
+
+	service.Broadcast(event(0))
+	sender.WaitStalled(t)
+
+	// when
+	for i := 1; i <= 3; i++ {
+		service.Broadcast(event(i))
+	}
+
+	// then
+	select {
+	case reason := <-disconnected:
+		assert.That(reason != nil, t.Errorf, "got no disconnect reason")
+	case <-time.After(Timeout):
+		t.Fatalf("client was not disconnected")
+	}
+	stats := service.Stats()
+	assert.That(len(stats) == 0, t.Errorf, "got stats for %d clients after disconnecting, want none", len(stats))
+}
+
+func TestServiceDoesNotDropRepliesWhenDroppingOldestMessages(t *testing.T) {
+	// given
+	service := notification.NewServiceWith(queueOptions(2, notification.DropOldest))
+	defer service.Close()
+
+	sender := NewStalledSender()
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+
+	service.Broadcast(event(0))
+	sender.WaitStalled(t)
+	service.Broadcast(event(1))
+	service.Broadcast(event(2))
+
+	// when
+	service.Reply(ClientID, reply(0))
+	for i := 3; i <= 5; i++ {
+		service.Broadcast(event(i))
+	}
+	sender.Release()
+
+	// then
+	sender.AssertGotMessages(t, "0", "reply 0", "4", "5")
+}
+
+func TestServiceDoesNotDropRepliesWhenDroppingNewestMessages(t *testing.T) {
+	// given
+	service := notification.NewServiceWith(queueOptions(2, notification.DropNewest))
+	defer service.Close()
+
+	sender := NewStalledSender()
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+
+	service.Broadcast(event(0))
+	sender.WaitStalled(t)
+	service.Broadcast(event(1))
+	service.Broadcast(event(2))
+
+	// when
+	service.Reply(ClientID, reply(0))
+	service.Broadcast(event(3))
+	sender.Release()
+
+	// then
+	sender.AssertGotMessages(t, "0", "1", "2", "reply 0")
+}
+
+func TestServiceDoesNotDisconnectClientOverAReply(t *testing.T) {
+	// given
+	service := notification.NewServiceWith(queueOptions(2, notification.Disconnect))
+	defer service.Close()
+
+	sender := NewStalledSender()
+	disconnected := make(chan error, 1)
+	service.Subscribe(ClientID, sender.Send, func(reason error) { disconnected <- reason })
+	defer service.Unsubscribe(ClientID)
+
+	service.Broadcast(event(0))
+	sender.WaitStalled(t)
+	service.Broadcast(event(1))
+	service.Broadcast(event(2))
+
+	// when
+	service.Reply(ClientID, reply(0))
+	sender.Release()
+
+	// then
+	sender.AssertGotMessages(t, "0", "1", "2", "reply 0")
+	select {
+	case reason := <-disconnected:
+		t.Errorf("client got disconnected: %s", reason)
+	default:
+	}
+}
+
+func queueOptions(size int, overflow string) notification.Options {
+	opts := notification.DefaultOptions()
+	opts.Queue = notification.QueueOptions{Size: size, Overflow: overflow}
+	return opts
+}
+
+func event(i int) munch.Event {
+	return munch.Event{Source: "src", Message: fmt.Sprint(i)}
+}
+
+// reply makes a stand-in for a reply, looking like an event to the senders.
+func reply(i int) munch.Event {
+	return munch.Event{Message: fmt.Sprint("reply ", i)}
+}
+
+func assertDropped(t *testing.T, service *notification.Service, want uint64) {
+	t.Helper()
+	stats := service.Stats()
+	assert.That(len(stats) == 1, t.Fatalf, "got stats for %d clients, want %d", len(stats), 1)
+	assert.That(stats[0].Dropped == want, t.Errorf, "got %d messages dropped, want %d", stats[0].Dropped, want)
+}
+
+// A StalledSender blocks sending the first message until released.
+type StalledSender struct {
+	SliceSender
+	stalled  chan struct{}
+	released chan struct{}
+}
+
+func NewStalledSender() *StalledSender {
+	return &StalledSender{
+		stalled:  make(chan struct{}, 1),
+		released: make(chan struct{}),
+	}
+}
+
+func (s *StalledSender) Send(msg interface{}) {
+	select {
+	case s.stalled <- struct{}{}:
+		<-s.released
+	default:
+	}
+	s.SliceSender.Send(msg)
+}
+
+func (s *StalledSender) WaitStalled(t *testing.T) {
+	t.Helper()
+	deadline := time.After(Timeout)
+	for len(s.stalled) == 0 {
+		select {
+		case <-deadline:
+			t.Fatalf("sender did not get a message")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func (s *StalledSender) Release() {
+	select {
+	case <-s.released:
+	default:
+		close(s.released)
+	}
+}
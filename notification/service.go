@@ -5,8 +5,11 @@
 package notification
 
 import (
+	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/szabba/assert"
 
@@ -15,7 +18,32 @@ import (
 
 type Service struct {
 	lock    sync.Mutex
-	clients map[munch.ClientID]sender
+	clients map[munch.ClientID]*client
+	history *history
+	queue   QueueOptions
+}
+
+// Options configure a Service. Each client gets a queue as described by
+// Queue. When History allows, the recent events broadcast are kept, and each
+// new client first gets those, and then the live ones, without any missing or
+// repeated in between.
+type Options struct {
+	History HistoryLimit
+	Queue   QueueOptions
+	Clock   func() time.Time
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Queue: DefaultQueueOptions(),
+		Clock: time.Now,
+	}
+}
+
+type client struct {
+	queue      *queue
+	filter     func(interface{}) bool
+	disconnect func(reason error)
 }
 
 type sender func(interface{})
@@ -24,29 +52,99 @@ func (s sender) send(msg interface{}) {
 	s(msg)
 }
 
+func (c *client) accepts(msg interface{}) bool {
+	return c.filter == nil || c.filter(msg)
+}
+
+// ClientStats describe the queue of a client.
+type ClientStats struct {
+	ID      munch.ClientID
+	Queued  int
+	Dropped uint64
+}
+
 func NewService() *Service {
+	return NewServiceWith(DefaultOptions())
+}
+
+func NewServiceWith(opts Options) *Service {
+	assert.That(opts.Queue.Validate() == nil, log.Panicf, "invalid queue options: %s", opts.Queue.Validate())
 	return &Service{
-		clients: make(map[munch.ClientID]sender),
+		clients: make(map[munch.ClientID]*client),
+		history: newHistory(opts.History, opts.Clock),
+		queue:   opts.Queue,
 	}
 }
 
-func (srv *Service) Subscribe(id munch.ClientID, sndr func(interface{})) {
+// Subscribe makes the service send messages to the client through sndr. When
+// the client falls too far behind and the queue overflow policy is to
+// disconnect, the client gets unsubscribed and disconnect gets called, unless
+// it is nil.
+func (srv *Service) Subscribe(id munch.ClientID, sndr func(interface{}), disconnect func(reason error)) {
+	srv.subscribe(id, sndr, disconnect, true)
+}
+
+// SubscribeLive is like Subscribe, but does not replay the recent events. It
+// is meant for clients subscribing again, which got those already.
+func (srv *Service) SubscribeLive(id munch.ClientID, sndr func(interface{}), disconnect func(reason error)) {
+	srv.subscribe(id, sndr, disconnect, false)
+}
+
+func (srv *Service) subscribe(id munch.ClientID, sndr func(interface{}), disconnect func(reason error), replay bool) {
 	srv.lock.Lock()
+	defer srv.lock.Unlock()
 	assert.That(sndr != nil, log.Panicf, "client %s registration attempted with nil sender", id)
-	srv.clients[id] = sndr
-	srv.lock.Unlock()
+
+	c := &client{queue: newQueue(srv.queue, sndr), disconnect: disconnect}
+	if replay {
+		c.queue.lock.Lock()
+		for _, evt := range srv.history.recent() {
+			c.queue.pushUnbounded(evt)
+		}
+		c.queue.lock.Unlock()
+	}
+	srv.clients[id] = c
+}
+
+// SetFilter makes the client only receive the broadcasts the filter accepts.
+// A nil filter lets all of them through. Targeted messages are not filtered.
+func (srv *Service) SetFilter(id munch.ClientID, filter func(interface{}) bool) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	c := srv.clients[id]
+	if c == nil {
+		log.Printf("got filter for unsubscribed client %s", id)
+		return
+	}
+	c.filter = filter
 }
 
 func (srv *Service) Send(id munch.ClientID, msg interface{}) {
 	srv.lock.Lock()
 	defer srv.lock.Unlock()
 
-	sender := srv.clients[id]
-	if sender == nil {
+	c := srv.clients[id]
+	if c == nil {
 		log.Printf("got message for unubscribed client %s: %#v", id, msg)
 		return
 	}
-	sender.send(msg)
+	srv.push(id, c, msg)
+}
+
+// Reply sends a reply to a message from the client. Unlike Send, it ignores
+// the queue overflow policy, so that no reply gets dropped, and no client gets
+// disconnected over one.
+func (srv *Service) Reply(id munch.ClientID, msg interface{}) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	c := srv.clients[id]
+	if c == nil {
+		log.Printf("got reply for unsubscribed client %s: %#v", id, msg)
+		return
+	}
+	c.queue.pushKept(msg)
 }
 
 func (srv *Service) Unsubscribe(id munch.ClientID) {
@@ -58,9 +156,28 @@ func (srv *Service) Unsubscribe(id munch.ClientID) {
 func (srv *Service) Broadcast(v interface{}) {
 	srv.lock.Lock()
 	defer srv.lock.Unlock()
-	for _, sender := range srv.clients {
-		sender.send(v)
+	if evt, ok := v.(munch.Event); ok {
+		srv.history.add(evt)
+	}
+	for id, c := range srv.clients {
+		if c.accepts(v) {
+			srv.push(id, c, v)
+		}
+	}
+}
+
+// Stats describe the queues of all the subscribed clients, ordered by ID.
+func (srv *Service) Stats() []ClientStats {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	stats := make([]ClientStats, 0, len(srv.clients))
+	for id, c := range srv.clients {
+		queued, dropped := c.queue.stats()
+		stats = append(stats, ClientStats{ID: id, Queued: queued, Dropped: dropped})
 	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID.Less(stats[j].ID) })
+	return stats
 }
 
 func (srv *Service) Close() {
@@ -76,6 +193,23 @@ func (srv *Service) Close() {
 	}
 }
 
+func (srv *Service) push(id munch.ClientID, c *client, msg interface{}) {
+	if c.queue.push(msg) {
+		return
+	}
+	reason := fmt.Errorf("more than %d messages queued", srv.queue.Size)
+	log.Printf("disconnecting client %s: %s", id, reason)
+	srv.unsubscribe(id)
+	if c.disconnect != nil {
+		go c.disconnect(reason)
+	}
+}
+
 func (srv *Service) unsubscribe(id munch.ClientID) {
+	c := srv.clients[id]
+	if c == nil {
+		return
+	}
+	c.queue.close()
 	delete(srv.clients, id)
 }
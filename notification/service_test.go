@@ -6,6 +6,7 @@ package notification_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/szabba/assert"
 
@@ -13,7 +14,12 @@ import (
 	"github.com/szabba/munch/notification"
 )
 
-const Message = "msg"
+const (
+	Message = "msg"
+
+	Timeout   = 5 * time.Second
+	SleepTime = 50 * time.Millisecond
+)
 
 var ClientID = new(munch.ClientIDGenerator).NextID()
 
@@ -24,7 +30,7 @@ func TestServiceSendsBroadcastToSubscribedClient(t *testing.T) {
 
 	sender := NewTestSender(t)
 
-	service.Subscribe(ClientID, sender.Send)
+	service.Subscribe(ClientID, sender.Send, nil)
 	defer service.Unsubscribe(ClientID)
 	msg := "msg"
 
@@ -42,7 +48,7 @@ func TestServiceDoesNotSendBroadcastToUnsubscribedClient(t *testing.T) {
 
 	sender := NewTestSender(t)
 
-	service.Subscribe(ClientID, sender.Send)
+	service.Subscribe(ClientID, sender.Send, nil)
 	service.Unsubscribe(ClientID)
 
 	msg := "msg"
@@ -64,7 +70,7 @@ func TestServiceSendsTargetedMessageToTheAddressee(t *testing.T) {
 	sender := NewTestSender(t)
 	msg := "msg"
 
-	service.Subscribe(id, sender.Send)
+	service.Subscribe(id, sender.Send, nil)
 	defer service.Unsubscribe(id)
 
 	// when
@@ -83,9 +89,9 @@ func TestServiceDoesNotSendTargtedMessageToADifferentSubscribedClient(t *testing
 	dstID, nonDstID := idGenerator.NextID(), idGenerator.NextID()
 	dst, nonDst := NewTestSender(t), NewTestSender(t)
 
-	service.Subscribe(dstID, dst.Send)
+	service.Subscribe(dstID, dst.Send, nil)
 	defer service.Unsubscribe(dstID)
-	service.Subscribe(nonDstID, nonDst.Send)
+	service.Subscribe(nonDstID, nonDst.Send, nil)
 	defer service.Unsubscribe(nonDstID)
 
 	msg := "msg"
@@ -108,7 +114,7 @@ func TestServiceDoesNotSendTargetedMessageToAnUnsubscribedClient(t *testing.T) {
 	sender := NewTestSender(t)
 	msg := "msg"
 
-	service.Subscribe(id, sender.Send)
+	service.Subscribe(id, sender.Send, nil)
 	service.Unsubscribe(id)
 
 	// when
@@ -118,40 +124,99 @@ func TestServiceDoesNotSendTargetedMessageToAnUnsubscribedClient(t *testing.T) {
 	sender.AssertGotNothing()
 }
 
+func TestServiceDoesNotSendBroadcastRejectedByClientFilter(t *testing.T) {
+	// given
+	service := notification.NewService()
+	defer service.Close()
+
+	sender := NewTestSender(t)
+
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+	service.SetFilter(ClientID, func(interface{}) bool { return false })
+
+	// when
+	service.Broadcast(Message)
+
+	// then
+	sender.AssertGotNothing()
+}
+
+func TestServiceSendsBroadcastAcceptedByClientFilter(t *testing.T) {
+	// given
+	service := notification.NewService()
+	defer service.Close()
+
+	sender := NewTestSender(t)
+
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+	service.SetFilter(ClientID, func(msg interface{}) bool { return msg == Message })
+
+	// when
+	service.Broadcast(Message)
+
+	// then
+	sender.AssertGotString(Message)
+}
+
+func TestServiceSendsTargetedMessageDespiteClientFilter(t *testing.T) {
+	// given
+	service := notification.NewService()
+	defer service.Close()
+
+	sender := NewTestSender(t)
+
+	service.Subscribe(ClientID, sender.Send, nil)
+	defer service.Unsubscribe(ClientID)
+	service.SetFilter(ClientID, func(interface{}) bool { return false })
+
+	// when
+	service.Send(ClientID, Message)
+
+	// then
+	sender.AssertGotString(Message)
+}
+
 type TestSender struct {
-	t       *testing.T
-	wasSent bool
-	msgGot  interface{}
+	t    *testing.T
+	msgs chan interface{}
 }
 
 func NewTestSender(t *testing.T) *TestSender {
-	return &TestSender{t: t}
+	return &TestSender{t: t, msgs: make(chan interface{}, 16)}
 }
 
 func (s *TestSender) Send(msg interface{}) {
-	s.wasSent = true
-	s.msgGot = msg
+	s.msgs <- msg
 }
 
 func (s *TestSender) AssertGotString(msgWant string) {
 	s.t.Helper()
 
-	assert.That(s.wasSent, s.t.Errorf, "no message was sent")
-	if !s.wasSent {
+	var msgGot interface{}
+	select {
+	case msgGot = <-s.msgs:
+	case <-time.After(Timeout):
+		s.t.Errorf("no message was sent")
 		return
 	}
 
-	msgGot, isString := s.msgGot.(string)
-	assert.That(isString, s.t.Errorf, "got message of type %T, want %T", s.msgGot, Message)
+	msgGotStr, isString := msgGot.(string)
+	assert.That(isString, s.t.Errorf, "got message of type %T, want %T", msgGot, Message)
 	if !isString {
 		return
 	}
 
-	assert.That(msgGot == Message, s.t.Errorf, "got message %q, want %q", msgGot, Message)
+	assert.That(msgGotStr == msgWant, s.t.Errorf, "got message %q, want %q", msgGotStr, msgWant)
 }
 
 func (s *TestSender) AssertGotNothing() {
 	s.t.Helper()
 
-	assert.That(!s.wasSent, s.t.Errorf, "unexpected message was sent: %#v", s.msgGot)
+	select {
+	case msg := <-s.msgs:
+		s.t.Errorf("unexpected message was sent: %#v", msg)
+	case <-time.After(SleepTime):
+	}
 }
@@ -9,3 +9,39 @@ import "github.com/szabba/munch"
 type EventConsumer interface {
 	On(munch.Event) error
 }
+
+// WithSource makes events that do not name their source come from the given
+// one.
+func WithSource(name string, cons EventConsumer) EventConsumer {
+	return sourceNamer{name, cons}
+}
+
+type sourceNamer struct {
+	name string
+	cons EventConsumer
+}
+
+func (sn sourceNamer) On(evt munch.Event) error {
+	if evt.Source == "" {
+		evt.Source = sn.name
+	}
+	return sn.cons.On(evt)
+}
+
+// Tee passes each event to all the consumers in turn. It stops at the first
+// one that fails.
+func Tee(conss ...EventConsumer) EventConsumer {
+	return tee(conss)
+}
+
+type tee []EventConsumer
+
+func (t tee) On(evt munch.Event) error {
+	for _, cons := range t {
+		err := cons.On(evt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,9 +5,14 @@
 package parsers_test
 
 import (
+	"errors"
+	"testing"
 	"time"
 
+	"github.com/szabba/assert"
+
 	"github.com/szabba/munch"
+	"github.com/szabba/munch/parsers"
 )
 
 func stepClock(start time.Time, dt time.Duration) func() time.Time {
@@ -37,3 +42,31 @@ func (sc *SliceConsumer) SetError(err error) { sc.err = err }
 func (sc *SliceConsumer) Len() int { return len(sc.evts) }
 
 func (sc *SliceConsumer) Event(i int) munch.Event { return sc.evts[i] }
+
+func TestTeePassesEventToAllConsumers(t *testing.T) {
+	// given
+	first, second := new(SliceConsumer), new(SliceConsumer)
+	cons := parsers.Tee(first, second)
+
+	// when
+	err := cons.On(munch.Event{Message: "msg"})
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(first.Len() == 1, t.Errorf, "first consumer got %d events, want 1", first.Len())
+	assert.That(second.Len() == 1, t.Errorf, "second consumer got %d events, want 1", second.Len())
+}
+
+func TestTeeStopsAtFailingConsumer(t *testing.T) {
+	// given
+	first, second := new(SliceConsumer), new(SliceConsumer)
+	first.SetError(errors.New("failed"))
+	cons := parsers.Tee(first, second)
+
+	// when
+	err := cons.On(munch.Event{Message: "msg"})
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, want one")
+	assert.That(second.Len() == 0, t.Errorf, "second consumer got %d events, want 0", second.Len())
+}
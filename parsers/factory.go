@@ -0,0 +1,99 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/sources"
+)
+
+const KindLines = "lines"
+
+// A Constructor builds a parser of some kind out of its JSON definition. The
+// parser submits the events it produces to cons.
+type Constructor func(def json.RawMessage, clock func() time.Time, cons EventConsumer) (io.WriteCloser, error)
+
+type Factory struct {
+	clock func() time.Time
+	cons  EventConsumer
+	kinds map[string]Constructor
+}
+
+var _ sources.ParserFactory = new(Factory)
+
+func NewFactory(clock func() time.Time, cons EventConsumer) *Factory {
+	fact := &Factory{
+		clock: clock,
+		cons:  cons,
+		kinds: make(map[string]Constructor),
+	}
+	fact.Register(KindLines, newLines)
+	fact.Register(KindGrok, newGrok)
+	fact.Register(KindJSON, newJSON)
+	fact.Register(KindLogfmt, newLogfmt)
+	return fact
+}
+
+func (fact *Factory) Register(kind string, ctor Constructor) {
+	fact.kinds[kind] = ctor
+}
+
+func (fact *Factory) NewParser(name string, def json.RawMessage) (io.WriteCloser, error) {
+	return fact.newParser(def, WithSource(name, fact.cons))
+}
+
+// ValidateParser builds a parser out of the definition and throws it away.
+// Parsers only start doing anything once written to, so that is safe.
+func (fact *Factory) ValidateParser(_ string, def json.RawMessage) error {
+	_, err := fact.newParser(def, discard{})
+	return err
+}
+
+func (fact *Factory) newParser(def json.RawMessage, cons EventConsumer) (io.WriteCloser, error) {
+	var header struct {
+		Kind string `json:"kind"`
+	}
+	err := json.Unmarshal(def, &header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parser definition: %s", err)
+	}
+	if header.Kind == "" {
+		return nil, fmt.Errorf("parser definition is missing a kind (known kinds: %s)", fact.knownKinds())
+	}
+
+	ctor := fact.kinds[header.Kind]
+	if ctor == nil {
+		return nil, fmt.Errorf("unknown parser kind %q (known kinds: %s)", header.Kind, fact.knownKinds())
+	}
+	parser, err := ctor(def, fact.clock, cons)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parser definition: %s", header.Kind, err)
+	}
+	return parser, nil
+}
+
+type discard struct{}
+
+func (discard) On(munch.Event) error { return nil }
+
+func (fact *Factory) knownKinds() string {
+	kinds := make([]string, 0, len(fact.kinds))
+	for kind := range fact.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ", ")
+}
+
+func newLines(def json.RawMessage, clock func() time.Time, cons EventConsumer) (io.WriteCloser, error) {
+	return newLinesFrom(def, clock, cons)
+}
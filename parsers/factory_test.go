@@ -0,0 +1,173 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/parsers"
+)
+
+func TestFactoryBuildsLinesParserWiredToTheConsumer(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{"kind": "lines"}`))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	io.WriteString(parser, "abba\n")
+
+	// then
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.Source == "src", t.Errorf, "got event source %q, want %q", evt.Source, "src")
+	assert.That(evt.Message == "abba", t.Errorf, "got event message %q, want %q", evt.Message, "abba")
+}
+
+func TestFactoryRejectsDefinitionWithoutKind(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{}`))
+
+	// then
+	assert.That(err != nil, t.Fatalf, "got no error, wanted one")
+	assert.That(parser == nil, t.Errorf, "got parser %#v, want %#v", parser, nil)
+}
+
+func TestFactoryRejectsInvalidDefinition(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`"lines"`))
+
+	// then
+	assert.That(err != nil, t.Fatalf, "got no error, wanted one")
+	assert.That(parser == nil, t.Errorf, "got parser %#v, want %#v", parser, nil)
+}
+
+func TestFactoryReportsUnknownKindWithTheKnownOnes(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{"kind": "xml"}`))
+
+	// then
+	assert.That(err != nil, t.Fatalf, "got no error, wanted one")
+	assert.That(parser == nil, t.Errorf, "got parser %#v, want %#v", parser, nil)
+	assert.That(strings.Contains(err.Error(), `"xml"`), t.Errorf, "error %q does not name the unknown kind", err)
+	assert.That(strings.Contains(err.Error(), "lines"), t.Errorf, "error %q does not list the known kinds", err)
+}
+
+func TestFactoryUsesRegisteredConstructor(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	errWant := errors.New("bad definition")
+	fact.Register("broken", func(_ json.RawMessage, _ func() time.Time, _ parsers.EventConsumer) (io.WriteCloser, error) {
+		return nil, errWant
+	})
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{"kind": "broken"}`))
+
+	// then
+	assert.That(err != nil, t.Fatalf, "got no error, wanted one")
+	assert.That(parser == nil, t.Errorf, "got parser %#v, want %#v", parser, nil)
+	assert.That(strings.Contains(err.Error(), errWant.Error()), t.Errorf, "error %q does not wrap %q", err, errWant)
+}
+
+func TestFactoryBuildsMultiLineParser(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	def := json.RawMessage(`{"kind": "lines", "multiline": {"start": "^\\d{4}-"}}`)
+
+	// when
+	parser, err := fact.NewParser("src", def)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	io.WriteString(parser, "2018-07-01 boom\njava.lang.Error\n  at Main\n2018-07-02 ok\n")
+	parser.Close()
+
+	// then
+	assert.That(cons.Len() == 2, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 2)
+	msgWant := "2018-07-01 boom\njava.lang.Error\n  at Main"
+	first := cons.Event(0)
+	assert.That(first.Message == msgWant, t.Errorf, "got first event message %q, want %q", first.Message, msgWant)
+}
+
+func TestFactoryBuildsMultiLineParserFlushingAfterTheDefaultTimeout(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	def := json.RawMessage(`{"kind": "lines", "multiline": {"indent": true}}`)
+	parser, err := fact.NewParser("src", def)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	defer parser.Close()
+
+	// when
+	io.WriteString(parser, "boom\n  at Main\n")
+	time.Sleep(parsers.DefaultMultiLineTimeout + SleepTime)
+	parser.Write(nil)
+
+	// then
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	msgWant := "boom\n  at Main"
+	assert.That(cons.Event(0).Message == msgWant, t.Errorf, "got event message %q, want %q", cons.Event(0).Message, msgWant)
+}
+
+func TestFactoryRejectsMultiLineParserWithoutARule(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{"kind": "lines", "multiline": {}}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(parser == nil, t.Errorf, "got parser %#v, want %#v", parser, nil)
+}
+
+func TestFactoryValidatesParserWithoutSubmittingEvents(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	okErr := fact.ValidateParser("src", json.RawMessage(`{"kind": "lines"}`))
+	badErr := fact.ValidateParser("src", json.RawMessage(`{"kind": "lines", "multiline": {}}`))
+
+	// then
+	assert.That(okErr == nil, t.Errorf, "unexpected error: %s", okErr)
+	assert.That(badErr != nil, t.Errorf, "got no error, wanted one")
+	assert.That(cons.Len() == 0, t.Errorf, "got %d events submitted, want %d", cons.Len(), 0)
+}
@@ -0,0 +1,205 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+const (
+	KindGrok = "grok"
+
+	// ParseFailureField is set on events a parser could not make sense of. Its
+	// value is the kind of the parser that failed.
+	ParseFailureField = "parse_failure"
+)
+
+// Grok pattern expansion follows the rules of the grok_exporter exporter
+// package. We cannot use that package directly, as it compiles the patterns
+// with the Oniguruma C library.
+var (
+	grokRefRE      = regexp.MustCompile(`%{(.+?)}`)
+	grokDefRE      = regexp.MustCompile(`^([A-Za-z0-9_]+)\s+(.+)$`)
+	onigNamedGroup = regexp.MustCompile(`\(\?<([A-Za-z_][A-Za-z0-9_]*)>`)
+)
+
+const grokMaxExpansions = 1000
+
+type GrokPatterns map[string]string
+
+func (ps GrokPatterns) Add(name, pattern string) {
+	ps[name] = pattern
+}
+
+// AddPath reads pattern definitions in the logstash format from a file or from
+// all the files in a directory.
+func (ps GrokPatterns) AddPath(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return ps.addFile(path)
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		err = ps.addFile(filepath.Join(path, file.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ps GrokPatterns) addFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ps.addFrom(path, f)
+}
+
+func (ps GrokPatterns) addFrom(path string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		match := grokDefRE.FindStringSubmatch(line)
+		if match == nil {
+			return fmt.Errorf("%s:%d: invalid grok pattern definition %q", path, lineNo, line)
+		}
+		ps.Add(match[1], match[2])
+	}
+	return scanner.Err()
+}
+
+// Compile expands all the %{NAME}, %{NAME:field} and %{NAME:field:type}
+// references in the pattern and compiles the result. Only references that
+// name a field become capturing groups.
+func (ps GrokPatterns) Compile(pattern string) (*regexp.Regexp, error) {
+	expanded, err := ps.expand(pattern)
+	if err != nil {
+		return nil, err
+	}
+	expanded = onigNamedGroup.ReplaceAllString(expanded, `(?P<$1>`)
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("grok pattern %q expands to an invalid regular expression: %s", pattern, err)
+	}
+	return re, nil
+}
+
+func (ps GrokPatterns) expand(pattern string) (string, error) {
+	out := pattern
+	for i := 0; i < grokMaxExpansions; i++ {
+		match := grokRefRE.FindStringSubmatch(out)
+		if match == nil {
+			return out, nil
+		}
+		parts := strings.Split(match[1], ":")
+		def, ok := ps[parts[0]]
+		if !ok {
+			return "", fmt.Errorf("grok pattern %s is not defined", match[0])
+		}
+		var replacement string
+		switch len(parts) {
+		case 1:
+			replacement = fmt.Sprintf("(?:%s)", def)
+		case 2, 3:
+			replacement = fmt.Sprintf("(?P<%s>%s)", parts[1], def)
+		default:
+			return "", fmt.Errorf("%s is not a valid grok pattern reference", match[0])
+		}
+		out = strings.Replace(out, match[0], replacement, -1)
+	}
+	return "", fmt.Errorf("grok pattern %q nests too deeply", pattern)
+}
+
+// Grok stores the named captures of a pattern matched against each event
+// message in the event fields.
+type Grok struct {
+	re   *regexp.Regexp
+	cons EventConsumer
+}
+
+var _ EventConsumer = new(Grok)
+
+func NewGrok(re *regexp.Regexp, cons EventConsumer) *Grok {
+	return &Grok{re: re, cons: cons}
+}
+
+func (g *Grok) On(evt munch.Event) error {
+	match := g.re.FindStringSubmatchIndex(evt.Message)
+	fields := make(map[string]string, len(evt.Fields)+g.re.NumSubexp())
+	for k, v := range evt.Fields {
+		fields[k] = v
+	}
+	if match == nil {
+		fields[ParseFailureField] = KindGrok
+	}
+	for i, name := range g.re.SubexpNames() {
+		if match == nil || name == "" || match[2*i] < 0 {
+			continue
+		}
+		fields[name] = evt.Message[match[2*i]:match[2*i+1]]
+	}
+	evt.Fields = fields
+	return g.cons.On(evt)
+}
+
+type grokDefinition struct {
+	Pattern      string            `json:"pattern"`
+	Patterns     map[string]string `json:"patterns"`
+	PatternPaths []string          `json:"patternPaths"`
+}
+
+func newGrok(rawDef json.RawMessage, clock func() time.Time, cons EventConsumer) (io.WriteCloser, error) {
+	var def grokDefinition
+	err := json.Unmarshal(rawDef, &def)
+	if err != nil {
+		return nil, err
+	}
+	if def.Pattern == "" {
+		return nil, fmt.Errorf("missing pattern")
+	}
+
+	patterns := DefaultGrokPatterns()
+	for _, path := range def.PatternPaths {
+		err = patterns.AddPath(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for name, pattern := range def.Patterns {
+		patterns.Add(name, pattern)
+	}
+
+	re, err := patterns.Compile(def.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return newLinesFrom(rawDef, clock, NewGrok(re, cons))
+}
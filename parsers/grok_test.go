@@ -0,0 +1,168 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/parsers"
+)
+
+func TestGrokStoresNamedCapturesInFields(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	re, err := parsers.DefaultGrokPatterns().Compile(`%{IP:client} %{WORD:method} %{URIPATHPARAM:path}`)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	grok := parsers.NewGrok(re, &cons)
+
+	// when
+	err = grok.On(munch.Event{Message: "10.0.0.1 GET /index.html?a=b"})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assertField(t, evt, "client", "10.0.0.1")
+	assertField(t, evt, "method", "GET")
+	assertField(t, evt, "path", "/index.html?a=b")
+	assertNoField(t, evt, parsers.ParseFailureField)
+}
+
+func TestGrokFlagsEventsThatDoNotMatch(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	re, err := parsers.DefaultGrokPatterns().Compile(`%{INT:n}`)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	grok := parsers.NewGrok(re, &cons)
+
+	// when
+	err = grok.On(munch.Event{Message: "no numbers here"})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.Message == "no numbers here", t.Errorf, "got event message %q, want %q", evt.Message, "no numbers here")
+	assertField(t, evt, parsers.ParseFailureField, parsers.KindGrok)
+	assertNoField(t, evt, "n")
+}
+
+func TestGrokPatternsFailToCompileUndefinedReference(t *testing.T) {
+	// when
+	_, err := parsers.DefaultGrokPatterns().Compile(`%{NO_SUCH_PATTERN:x}`)
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+}
+
+func TestGrokPatternsAcceptOnigurumaStyleNamedGroups(t *testing.T) {
+	// given
+	patterns := parsers.DefaultGrokPatterns()
+	patterns.Add("KV", `(?<key>\w+)=(?<value>\w+)`)
+
+	// when
+	re, err := patterns.Compile(`%{KV}`)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	match := re.FindStringSubmatch("a=b")
+	assert.That(len(match) == 3, t.Fatalf, "got match %q, want 3 elements", match)
+	assert.That(match[re.SubexpIndex("value")] == "b", t.Errorf, "got value %q, want %q", match[re.SubexpIndex("value")], "b")
+}
+
+func TestGrokPatternsLoadDefinitionsFromADirectory(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "munch-grok")
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	defer os.RemoveAll(dir)
+
+	content := "# a comment\n\nTHREEDIGITS \\d{3}\n"
+	err = ioutil.WriteFile(filepath.Join(dir, "custom"), []byte(content), 0644)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	patterns := parsers.DefaultGrokPatterns()
+
+	// when
+	err = patterns.AddPath(dir)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	re, err := patterns.Compile(`%{THREEDIGITS:code}`)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(re.MatchString("404"), t.Errorf, "pattern %s does not match %q", re, "404")
+}
+
+func TestFactoryBuildsGrokParser(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	def := json.RawMessage(`{
+		"kind": "grok",
+		"pattern": "%{LEVEL:level} %{GREEDYDATA:rest}",
+		"patterns": {"LEVEL": "[A-Z]+"}
+	}`)
+
+	// when
+	parser, err := fact.NewParser("src", def)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	io.WriteString(parser, "WARN disk almost full\n")
+
+	// then
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assertField(t, evt, "level", "WARN")
+	assertField(t, evt, "rest", "disk almost full")
+}
+
+func TestFactoryRejectsGrokParserWithoutPattern(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{"kind": "grok"}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(parser == nil, t.Errorf, "got parser %#v, want %#v", parser, nil)
+}
+
+func TestDefaultGrokPatternsAllCompile(t *testing.T) {
+	// given
+	patterns := parsers.DefaultGrokPatterns()
+
+	for name := range patterns {
+		// when
+		_, err := patterns.Compile("%{" + name + "}")
+
+		// then
+		assert.That(err == nil, t.Errorf, "pattern %s: %s", name, err)
+	}
+}
+
+func assertField(t *testing.T, evt munch.Event, key, want string) {
+	t.Helper()
+	got, ok := evt.Fields[key]
+	assert.That(ok, t.Errorf, "event has no field %q", key)
+	assert.That(got == want, t.Errorf, "got field %q = %q, want %q", key, got, want)
+}
+
+func assertNoField(t *testing.T, evt munch.Event, key string) {
+	t.Helper()
+	got, ok := evt.Fields[key]
+	assert.That(!ok, t.Errorf, "got unexpected field %q = %q", key, got)
+}
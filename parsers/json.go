@@ -0,0 +1,155 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+const KindJSON = "json"
+
+type JSONOptions struct {
+	MessageKey string `json:"messageKey"`
+	TimeKey    string `json:"timeKey"`
+	TimeLayout string `json:"timeLayout"`
+}
+
+func DefaultJSONOptions() JSONOptions {
+	return JSONOptions{
+		MessageKey: "msg",
+		TimeKey:    "time",
+		TimeLayout: time.RFC3339Nano,
+	}
+}
+
+// JSON decodes event messages holding a single JSON object each. The message
+// and time keys become the event message and time, while all the other keys
+// get flattened into the event fields. Nested keys are joined with dots. A
+// record without a message key keeps the whole line as its message.
+type JSON struct {
+	opts JSONOptions
+	cons EventConsumer
+}
+
+var _ EventConsumer = new(JSON)
+
+func NewJSON(opts JSONOptions, cons EventConsumer) *JSON {
+	return &JSON{opts: opts, cons: cons}
+}
+
+func (j *JSON) On(evt munch.Event) error {
+	record, err := j.decode(evt.Message)
+	if err != nil {
+		evt.Fields = withField(evt.Fields, ParseFailureField, KindJSON)
+		return j.cons.On(evt)
+	}
+
+	fields := make(map[string]string, len(evt.Fields)+len(record))
+	for k, v := range evt.Fields {
+		fields[k] = v
+	}
+	for k, v := range record {
+		switch {
+		case k == j.opts.MessageKey:
+			evt.Message = flatValue(v)
+		case k == j.opts.TimeKey && j.setTime(&evt, v):
+		default:
+			flatten(fields, k, v)
+		}
+	}
+	evt.Fields = fields
+	return j.cons.On(evt)
+}
+
+func (j *JSON) decode(line string) (map[string]interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var record map[string]interface{}
+	err := dec.Decode(&record)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("not a JSON object: %s", line)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("trailing data after JSON object: %s", line)
+	}
+	return record, nil
+}
+
+func (j *JSON) setTime(evt *munch.Event, v interface{}) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	at, err := time.Parse(j.opts.TimeLayout, s)
+	if err != nil {
+		return false
+	}
+	evt.At = at
+	return true
+}
+
+func flatten(fields map[string]string, prefix string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range v {
+			flatten(fields, prefix+"."+k, inner)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			flatten(fields, prefix+"."+strconv.Itoa(i), inner)
+		}
+	default:
+		fields[prefix] = flatValue(v)
+	}
+}
+
+func flatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(v)
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+}
+
+func withField(fields map[string]string, key, value string) map[string]string {
+	out := make(map[string]string, len(fields)+1)
+	for k, v := range fields {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+func newJSON(rawDef json.RawMessage, clock func() time.Time, cons EventConsumer) (io.WriteCloser, error) {
+	opts := DefaultJSONOptions()
+	err := json.Unmarshal(rawDef, &opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MessageKey == "" {
+		return nil, fmt.Errorf("empty message key")
+	}
+	return newLinesFrom(rawDef, clock, NewJSON(opts, cons))
+}
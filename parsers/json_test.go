@@ -0,0 +1,133 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers_test
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/parsers"
+)
+
+func TestJSONMapsMessageAndTimeKeys(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	parser := parsers.NewJSON(parsers.DefaultJSONOptions(), &cons)
+	atWant := time.Date(2018, 7, 1, 12, 30, 0, 0, time.UTC)
+
+	// when
+	err := parser.On(munch.Event{
+		At:      time.Unix(0, 0),
+		Message: `{"msg": "started", "time": "2018-07-01T12:30:00Z"}`,
+	})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.Message == "started", t.Errorf, "got event message %q, want %q", evt.Message, "started")
+	assert.That(evt.At.Equal(atWant), t.Errorf, "got event at %v, want %v", evt.At, atWant)
+	assert.That(len(evt.Fields) == 0, t.Errorf, "got unexpected fields %v", evt.Fields)
+}
+
+func TestJSONFlattensOtherKeysIntoFields(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	parser := parsers.NewJSON(parsers.DefaultJSONOptions(), &cons)
+
+	// when
+	err := parser.On(munch.Event{
+		Message: `{"msg": "m", "level": "info", "n": 1.5, "ok": true, "none": null, "req": {"id": 7, "tags": ["a", "b"]}}`,
+	})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assertField(t, evt, "level", "info")
+	assertField(t, evt, "n", "1.5")
+	assertField(t, evt, "ok", "true")
+	assertField(t, evt, "none", "")
+	assertField(t, evt, "req.id", "7")
+	assertField(t, evt, "req.tags.0", "a")
+	assertField(t, evt, "req.tags.1", "b")
+}
+
+func TestJSONUsesConfiguredKeysAndLayout(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	opts := parsers.JSONOptions{MessageKey: "message", TimeKey: "ts", TimeLayout: "2006-01-02 15:04:05"}
+	parser := parsers.NewJSON(opts, &cons)
+	atWant := time.Date(2018, 7, 1, 12, 30, 0, 0, time.UTC)
+
+	// when
+	err := parser.On(munch.Event{Message: `{"message": "hi", "ts": "2018-07-01 12:30:00", "msg": "other"}`})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.Message == "hi", t.Errorf, "got event message %q, want %q", evt.Message, "hi")
+	assert.That(evt.At.Equal(atWant), t.Errorf, "got event at %v, want %v", evt.At, atWant)
+	assertField(t, evt, "msg", "other")
+}
+
+func TestJSONKeepsUnparsableTimeAsField(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	parser := parsers.NewJSON(parsers.DefaultJSONOptions(), &cons)
+	atWant := time.Unix(5, 0)
+
+	// when
+	err := parser.On(munch.Event{At: atWant, Message: `{"msg": "m", "time": "yesterday"}`})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.At.Equal(atWant), t.Errorf, "got event at %v, want %v", evt.At, atWant)
+	assertField(t, evt, "time", "yesterday")
+}
+
+func TestJSONPassesInvalidLinesThroughAsRawMessages(t *testing.T) {
+	for _, line := range []string{`not json`, `[1, 2]`, `{"msg": "a"} trailing`, `null`} {
+		// given
+		var cons SliceConsumer
+		parser := parsers.NewJSON(parsers.DefaultJSONOptions(), &cons)
+
+		// when
+		err := parser.On(munch.Event{Message: line})
+
+		// then
+		assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+		assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+		evt := cons.Event(0)
+		assert.That(evt.Message == line, t.Errorf, "got event message %q, want %q", evt.Message, line)
+		assertField(t, evt, parsers.ParseFailureField, parsers.KindJSON)
+	}
+}
+
+func TestFactoryBuildsJSONParser(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{"kind": "json", "messageKey": "message"}`))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	io.WriteString(parser, `{"message": "hello", "user": "ann"}`+"\n")
+
+	// then
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.Message == "hello", t.Errorf, "got event message %q, want %q", evt.Message, "hello")
+	assertField(t, evt, "user", "ann")
+}
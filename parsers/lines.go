@@ -6,29 +6,61 @@ package parsers
 
 import (
 	"bytes"
+	"sync"
 	"time"
 
 	"github.com/szabba/munch"
 )
 
 type Lines struct {
+	lock  sync.Mutex
 	clock func() time.Time
 	cons  EventConsumer
 	buf   bytes.Buffer
+
+	continues  func(line string) bool
+	flushAfter time.Duration
+	record     *munch.Event
+	timer      *time.Timer
+	timerGen   int
+	timerErr   error
 }
 
 func NewLines(clock func() time.Time, cons EventConsumer) *Lines {
 	return &Lines{clock: clock, cons: cons}
 }
 
+// GroupRecords makes l join lines for which continues holds to the record
+// started by the line before them, so that a multi-line record becomes a
+// single event. As the end of a record is only known once the next one starts,
+// a positive flushAfter makes l submit a record once no line has been added to
+// it for that long.
+//
+// Submitting a record after the timeout can fail with no write to report the
+// error from. Such an error is sticky: every later Write and Close fails with
+// it, without consuming any input.
+func (l *Lines) GroupRecords(continues func(line string) bool, flushAfter time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.continues = continues
+	l.flushAfter = flushAfter
+}
+
 func (l *Lines) Write(p []byte) (n int, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.timerErr != nil {
+		return 0, l.timerErr
+	}
+
 	var line []byte
 	for len(p) > 0 && err == nil {
 		line, p, n = l.writeLine(p, n)
 		if line == nil {
 			continue
 		}
-		err := l.submitEvent(string(line))
+		err := l.submitLine(string(line))
 		l.buf.Reset()
 		if err != nil {
 			return n, err
@@ -38,9 +70,32 @@ func (l *Lines) Write(p []byte) (n int, err error) {
 }
 
 func (l *Lines) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.timerErr != nil {
+		l.stopTimer()
+		return l.timerErr
+	}
+
 	line := l.buf.String()
 	l.buf.Reset()
-	return l.submitEvent(line)
+	if l.continues == nil && line == "" {
+		return nil
+	}
+	if l.continues == nil {
+		return l.submitEvent(line)
+	}
+
+	var err error
+	if line != "" {
+		err = l.submitLine(line)
+	}
+	if err == nil {
+		err = l.flushRecord()
+	}
+	l.stopTimer()
+	return err
 }
 
 func (l *Lines) writeLine(p []byte, n int) (line, left []byte, n2 int) {
@@ -54,6 +109,62 @@ func (l *Lines) writeLine(p []byte, n int) (line, left []byte, n2 int) {
 	return l.buf.Bytes(), suffix, n + len(prefix) + 1
 }
 
+func (l *Lines) submitLine(line string) error {
+	if l.continues == nil {
+		return l.submitEvent(line)
+	}
+
+	if l.record != nil && l.continues(line) {
+		l.record.Message += "\n" + line
+		l.resetTimer()
+		return nil
+	}
+
+	err := l.flushRecord()
+	l.record = &munch.Event{At: l.clock(), Message: line}
+	l.resetTimer()
+	return err
+}
+
+func (l *Lines) flushRecord() error {
+	if l.record == nil {
+		return nil
+	}
+	evt := *l.record
+	l.record = nil
+	return l.cons.On(evt)
+}
+
+func (l *Lines) resetTimer() {
+	if l.flushAfter <= 0 {
+		return
+	}
+	l.stopTimer()
+	gen := l.timerGen
+	l.timer = time.AfterFunc(l.flushAfter, func() { l.onTimeout(gen) })
+}
+
+func (l *Lines) stopTimer() {
+	l.timerGen++
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+}
+
+func (l *Lines) onTimeout(gen int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if gen != l.timerGen {
+		return
+	}
+	err := l.flushRecord()
+	if err != nil && l.timerErr == nil {
+		l.timerErr = err
+	}
+}
+
 func (l *Lines) submitEvent(msg string) error {
 	now := l.clock()
 	evt := munch.Event{At: now, Message: msg}
@@ -6,6 +6,7 @@ package parsers_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +14,8 @@ import (
 	"github.com/szabba/munch/parsers"
 )
 
+const SleepTime = 100 * time.Millisecond
+
 func TestLinesDoNothingForEmptyInput(t *testing.T) {
 	// given
 	clock := stepClock(time.Unix(0, 0), time.Second)
@@ -149,3 +152,103 @@ func TestLinesReportsTheRetainedInputAsEventUponBeingClosed(t *testing.T) {
 	assert.That(evt.At.Equal(time.Unix(0, 0)), t.Errorf, "got event at %v, want %v", evt.At, time.Unix(0, 0))
 	assert.That(evt.Message == "abba", t.Errorf, "got event message %q, want %q", evt.Message, "abba")
 }
+
+func TestLinesSubmitNothingUponBeingClosedWithNothingRetained(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	lines := parsers.NewLines(clock, &cons)
+
+	lines.Write([]byte("abba\n"))
+
+	// when
+	err := lines.Close()
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Errorf, "got %d events submitted, want %d", cons.Len(), 1)
+}
+
+func TestLinesGroupsContinuationLinesIntoOneEvent(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	lines := parsers.NewLines(clock, &cons)
+	lines.GroupRecords(func(line string) bool { return strings.HasPrefix(line, "\t") }, 0)
+
+	// when
+	_, err := lines.Write([]byte("panic: oops\n\tmain.go:1\n\tmain.go:2\nnext\n"))
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error %q", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+
+	evt := cons.Event(0)
+	msgWant := "panic: oops\n\tmain.go:1\n\tmain.go:2"
+	assert.That(evt.At == time.Unix(0, 0), t.Errorf, "got event at %v, want %v", evt.At, time.Unix(0, 0))
+	assert.That(evt.Message == msgWant, t.Errorf, "got event message %q, want %q", evt.Message, msgWant)
+}
+
+func TestLinesSubmitsTheLastRecordUponBeingClosed(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	lines := parsers.NewLines(clock, &cons)
+	lines.GroupRecords(func(line string) bool { return strings.HasPrefix(line, " ") }, 0)
+
+	lines.Write([]byte("first\nsecond\n more"))
+
+	// when
+	err := lines.Close()
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 2, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 2)
+
+	second := cons.Event(1)
+	assert.That(second.At == time.Unix(1, 0), t.Errorf, "got second event at %v, want %v", second.At, time.Unix(1, 0))
+	assert.That(second.Message == "second\n more", t.Errorf, "got second event message %q, want %q", second.Message, "second\n more")
+}
+
+func TestLinesSubmitsTheLastRecordAfterTheFlushTimeout(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	lines := parsers.NewLines(clock, &cons)
+	lines.GroupRecords(func(line string) bool { return strings.HasPrefix(line, " ") }, SleepTime/10)
+
+	// when
+	lines.Write([]byte("first\n more\n"))
+	time.Sleep(SleepTime)
+	lines.Write(nil)
+
+	// then
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+
+	evt := cons.Event(0)
+	assert.That(evt.Message == "first\n more", t.Errorf, "got event message %q, want %q", evt.Message, "first\n more")
+}
+
+func TestLinesKeepFailingAfterTheFlushTimeoutFails(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	lines := parsers.NewLines(clock, &cons)
+	lines.GroupRecords(func(line string) bool { return strings.HasPrefix(line, " ") }, SleepTime/10)
+
+	errWant := errors.New("consumer error")
+	cons.SetError(errWant)
+	lines.Write([]byte("first\n more\n"))
+	time.Sleep(SleepTime)
+
+	// when
+	n1, err1 := lines.Write([]byte("second\n"))
+	n2, err2 := lines.Write([]byte("second\n"))
+	closeErr := lines.Close()
+
+	// then
+	assert.That(n1 == 0 && n2 == 0, t.Errorf, "got %d and %d bytes read, want none", n1, n2)
+	assert.That(err1 == errWant, t.Errorf, "got first error %v, want %q", err1, errWant)
+	assert.That(err2 == errWant, t.Errorf, "got second error %v, want %q", err2, errWant)
+	assert.That(closeErr == errWant, t.Errorf, "got close error %v, want %q", closeErr, errWant)
+}
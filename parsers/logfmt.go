@@ -0,0 +1,179 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+const KindLogfmt = "logfmt"
+
+type LogfmtOptions struct {
+	MessageKey string   `json:"messageKey"`
+	TimeKeys   []string `json:"timeKeys"`
+	TimeLayout string   `json:"timeLayout"`
+}
+
+func DefaultLogfmtOptions() LogfmtOptions {
+	return LogfmtOptions{
+		MessageKey: "msg",
+		TimeKeys:   []string{"ts", "time"},
+		TimeLayout: time.RFC3339Nano,
+	}
+}
+
+// Logfmt decodes event messages made up of key=value pairs. Values containing
+// spaces, quotes or equal signs are double-quoted, using Go string escapes. A
+// key without a value gets an empty one.
+type Logfmt struct {
+	opts LogfmtOptions
+	cons EventConsumer
+}
+
+var _ EventConsumer = new(Logfmt)
+
+func NewLogfmt(opts LogfmtOptions, cons EventConsumer) *Logfmt {
+	return &Logfmt{opts: opts, cons: cons}
+}
+
+func (l *Logfmt) On(evt munch.Event) error {
+	pairs, err := decodeLogfmt(evt.Message)
+	if err != nil || len(pairs) == 0 {
+		evt.Fields = withField(evt.Fields, ParseFailureField, KindLogfmt)
+		return l.cons.On(evt)
+	}
+
+	fields := make(map[string]string, len(evt.Fields)+len(pairs))
+	for k, v := range evt.Fields {
+		fields[k] = v
+	}
+	for _, p := range pairs {
+		switch {
+		case p.key == l.opts.MessageKey:
+			evt.Message = p.value
+		case l.isTimeKey(p.key) && l.setTime(&evt, p.value):
+		default:
+			fields[p.key] = p.value
+		}
+	}
+	evt.Fields = fields
+	return l.cons.On(evt)
+}
+
+func (l *Logfmt) isTimeKey(key string) bool {
+	for _, timeKey := range l.opts.TimeKeys {
+		if key == timeKey {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Logfmt) setTime(evt *munch.Event, value string) bool {
+	at, err := time.Parse(l.opts.TimeLayout, value)
+	if err != nil {
+		return false
+	}
+	evt.At = at
+	return true
+}
+
+type logfmtPair struct {
+	key, value string
+}
+
+func decodeLogfmt(line string) ([]logfmtPair, error) {
+	var pairs []logfmtPair
+	i := 0
+	for {
+		for i < len(line) && line[i] <= ' ' {
+			i++
+		}
+		if i == len(line) {
+			return pairs, nil
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("unexpected %q at offset %d", line[i], i)
+		}
+		key := line[start:i]
+
+		if i == len(line) || line[i] <= ' ' {
+			pairs = append(pairs, logfmtPair{key, ""})
+			continue
+		}
+		if line[i] == '"' {
+			return nil, fmt.Errorf("unexpected '\"' in key at offset %d", i)
+		}
+
+		i++
+		var value string
+		var err error
+		if i < len(line) && line[i] == '"' {
+			value, i, err = decodeLogfmtQuoted(line, i)
+		} else {
+			value, i, err = decodeLogfmtBare(line, i)
+		}
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, logfmtPair{key, value})
+	}
+}
+
+func decodeLogfmtQuoted(line string, start int) (string, int, error) {
+	i := start + 1
+	for i < len(line) && line[i] != '"' {
+		if line[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i >= len(line) {
+		return "", 0, fmt.Errorf("unterminated quoted value at offset %d", start)
+	}
+	i++
+	value, err := strconv.Unquote(line[start:i])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid quoted value at offset %d: %s", start, err)
+	}
+	if i < len(line) && line[i] > ' ' {
+		return "", 0, fmt.Errorf("unexpected %q after quoted value at offset %d", line[i], i)
+	}
+	return value, i, nil
+}
+
+func decodeLogfmtBare(line string, start int) (string, int, error) {
+	i := start
+	for i < len(line) && line[i] > ' ' {
+		if line[i] == '"' || line[i] == '=' {
+			return "", 0, fmt.Errorf("unexpected %q in unquoted value at offset %d", line[i], i)
+		}
+		i++
+	}
+	return line[start:i], i, nil
+}
+
+func newLogfmt(rawDef json.RawMessage, clock func() time.Time, cons EventConsumer) (io.WriteCloser, error) {
+	opts := DefaultLogfmtOptions()
+	err := json.Unmarshal(rawDef, &opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MessageKey == "" {
+		return nil, fmt.Errorf("empty message key")
+	}
+	return newLinesFrom(rawDef, clock, NewLogfmt(opts, cons))
+}
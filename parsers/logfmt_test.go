@@ -0,0 +1,107 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers_test
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/parsers"
+)
+
+func TestLogfmtMapsMessageTimeAndFields(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	parser := parsers.NewLogfmt(parsers.DefaultLogfmtOptions(), &cons)
+	atWant := time.Date(2018, 7, 1, 12, 30, 0, 0, time.UTC)
+
+	// when
+	err := parser.On(munch.Event{Message: `ts=2018-07-01T12:30:00Z level=info msg="started" dur=3ms`})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.Message == "started", t.Errorf, "got event message %q, want %q", evt.Message, "started")
+	assert.That(evt.At.Equal(atWant), t.Errorf, "got event at %v, want %v", evt.At, atWant)
+	assert.That(len(evt.Fields) == 2, t.Errorf, "got fields %v, want 2 of them", evt.Fields)
+	assertField(t, evt, "level", "info")
+	assertField(t, evt, "dur", "3ms")
+}
+
+func TestLogfmtUnescapesQuotedValues(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	parser := parsers.NewLogfmt(parsers.DefaultLogfmtOptions(), &cons)
+
+	// when
+	err := parser.On(munch.Event{Message: `msg="say \"hi\"\n" path="a b=c" empty="" flag`})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.Message == "say \"hi\"\n", t.Errorf, "got event message %q, want %q", evt.Message, "say \"hi\"\n")
+	assertField(t, evt, "path", "a b=c")
+	assertField(t, evt, "empty", "")
+	assertField(t, evt, "flag", "")
+}
+
+func TestLogfmtUsesTimeKeyFallback(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	parser := parsers.NewLogfmt(parsers.DefaultLogfmtOptions(), &cons)
+	atWant := time.Date(2018, 7, 1, 12, 30, 0, 0, time.UTC)
+
+	// when
+	err := parser.On(munch.Event{Message: `time=2018-07-01T12:30:00Z msg=x`})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.At.Equal(atWant), t.Errorf, "got event at %v, want %v", evt.At, atWant)
+}
+
+func TestLogfmtPassesMalformedLinesThroughAsRawMessages(t *testing.T) {
+	for _, line := range []string{``, `msg="unterminated`, `a="x"y`, `a=b"c`, `=value`, `"key"=v`} {
+		// given
+		var cons SliceConsumer
+		parser := parsers.NewLogfmt(parsers.DefaultLogfmtOptions(), &cons)
+
+		// when
+		err := parser.On(munch.Event{Message: line})
+
+		// then
+		assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+		assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+		evt := cons.Event(0)
+		assert.That(evt.Message == line, t.Errorf, "got event message %q, want %q", evt.Message, line)
+		assertField(t, evt, parsers.ParseFailureField, parsers.KindLogfmt)
+	}
+}
+
+func TestFactoryBuildsLogfmtParser(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{"kind": "logfmt"}`))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	io.WriteString(parser, "level=warn msg=slow\n")
+
+	// then
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.Message == "slow", t.Errorf, "got event message %q, want %q", evt.Message, "slow")
+	assertField(t, evt, "level", "warn")
+}
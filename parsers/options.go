@@ -0,0 +1,93 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+// LineOptions are understood by all the parser kinds that split their input
+// into lines.
+type LineOptions struct {
+	MultiLine *MultiLineOptions `json:"multiline"`
+	Timestamp *TimestampOptions `json:"timestamp"`
+}
+
+// DefaultMultiLineTimeout is how long a record waits for more lines when the
+// options do not say.
+const DefaultMultiLineTimeout = time.Second
+
+// MultiLineOptions decide which lines continue the record started by an
+// earlier one. A line continues a record when it does not match Start, or when
+// Indent is set and the line begins with whitespace.
+//
+// A record gets submitted once no line has been added to it for the Timeout,
+// which defaults to DefaultMultiLineTimeout.
+type MultiLineOptions struct {
+	Start   string         `json:"start"`
+	Indent  bool           `json:"indent"`
+	Timeout munch.Duration `json:"timeout"`
+}
+
+func newLinesFrom(rawDef json.RawMessage, clock func() time.Time, cons EventConsumer) (*Lines, error) {
+	var opts LineOptions
+	err := json.Unmarshal(rawDef, &opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timestamp != nil {
+		cons, err = opts.Timestamp.wrap(cons)
+		if err != nil {
+			return nil, err
+		}
+	}
+	lines := NewLines(clock, cons)
+	if opts.MultiLine == nil {
+		return lines, nil
+	}
+	continues, err := opts.MultiLine.continuation()
+	if err != nil {
+		return nil, err
+	}
+	lines.GroupRecords(continues, opts.MultiLine.timeout())
+	return lines, nil
+}
+
+func (opts MultiLineOptions) continuation() (func(string) bool, error) {
+	if opts.Start == "" && !opts.Indent {
+		return nil, fmt.Errorf("multiline mode needs a start pattern or the indent rule")
+	}
+	if opts.Timeout < 0 {
+		return nil, fmt.Errorf("negative multiline timeout: %s", time.Duration(opts.Timeout))
+	}
+
+	var start *regexp.Regexp
+	if opts.Start != "" {
+		var err error
+		start, err = regexp.Compile(opts.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multiline start pattern: %s", err)
+		}
+	}
+
+	return func(line string) bool {
+		if opts.Indent && line != "" && (line[0] == ' ' || line[0] == '\t') {
+			return true
+		}
+		return start != nil && !start.MatchString(line)
+	}, nil
+}
+
+func (opts MultiLineOptions) timeout() time.Duration {
+	if opts.Timeout == 0 {
+		return DefaultMultiLineTimeout
+	}
+	return time.Duration(opts.Timeout)
+}
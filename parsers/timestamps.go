@@ -0,0 +1,138 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+const (
+	TimestampRFC3339 = "rfc3339"
+	TimestampSyslog  = "syslog"
+	TimestampApache  = "apache"
+)
+
+type timestampFormat struct {
+	pattern string
+	layouts []string
+}
+
+var wellKnownTimestamps = map[string]timestampFormat{
+	TimestampRFC3339: {
+		pattern: `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})`,
+		layouts: []string{
+			"2006-01-02T15:04:05.999999999Z07:00",
+			"2006-01-02 15:04:05.999999999Z07:00",
+			"2006-01-02T15:04:05.999999999Z0700",
+			"2006-01-02 15:04:05.999999999Z0700",
+		},
+	},
+	TimestampSyslog: {
+		pattern: `[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}`,
+		layouts: []string{time.Stamp},
+	},
+	TimestampApache: {
+		pattern: `\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+		layouts: []string{"02/Jan/2006:15:04:05 -0700"},
+	},
+}
+
+// Timestamps sets the time of an event from a timestamp found in its message.
+// The timestamp is the first submatch of the pattern, or the whole match when
+// the pattern has no groups. It is parsed with the first layout that accepts
+// it. Events without a usable timestamp keep the time they came with.
+//
+// Timestamps without a year, like the syslog ones, are assumed to come from
+// the year the event was received in.
+type Timestamps struct {
+	pattern *regexp.Regexp
+	layouts []string
+	cons    EventConsumer
+}
+
+var _ EventConsumer = new(Timestamps)
+
+func NewTimestamps(pattern *regexp.Regexp, layouts []string, cons EventConsumer) *Timestamps {
+	return &Timestamps{pattern: pattern, layouts: layouts, cons: cons}
+}
+
+// WellKnownTimestamps extracts timestamps in one of the formats named by the
+// Timestamp* constants.
+func WellKnownTimestamps(format string, cons EventConsumer) (*Timestamps, error) {
+	known, ok := wellKnownTimestamps[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown timestamp format %q (known formats: %s)", format, knownTimestampFormats())
+	}
+	return NewTimestamps(regexp.MustCompile(known.pattern), known.layouts, cons), nil
+}
+
+func (ts *Timestamps) On(evt munch.Event) error {
+	at, ok := ts.extract(evt.Message, evt.At)
+	if ok {
+		evt.At = at
+	}
+	return ts.cons.On(evt)
+}
+
+func (ts *Timestamps) extract(msg string, received time.Time) (time.Time, bool) {
+	match := ts.pattern.FindStringSubmatch(msg)
+	if match == nil {
+		return time.Time{}, false
+	}
+	raw := match[0]
+	if len(match) > 1 {
+		raw = match[1]
+	}
+
+	for _, layout := range ts.layouts {
+		at, err := time.ParseInLocation(layout, raw, received.Location())
+		if err != nil {
+			continue
+		}
+		if at.Year() == 0 {
+			at = at.AddDate(received.Year(), 0, 0)
+		}
+		return at, true
+	}
+	return time.Time{}, false
+}
+
+type TimestampOptions struct {
+	Format  string   `json:"format"`
+	Pattern string   `json:"pattern"`
+	Layouts []string `json:"layouts"`
+}
+
+func (opts TimestampOptions) wrap(cons EventConsumer) (EventConsumer, error) {
+	switch {
+	case opts.Format != "" && (opts.Pattern != "" || len(opts.Layouts) > 0):
+		return nil, fmt.Errorf("timestamp format cannot be combined with a pattern or layouts")
+	case opts.Format != "":
+		return WellKnownTimestamps(opts.Format, cons)
+	case opts.Pattern == "" || len(opts.Layouts) == 0:
+		return nil, fmt.Errorf("timestamp extraction needs a format, or a pattern with layouts")
+	}
+
+	pattern, err := regexp.Compile(opts.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp pattern: %s", err)
+	}
+	return NewTimestamps(pattern, opts.Layouts, cons), nil
+}
+
+func knownTimestampFormats() string {
+	formats := make([]string, 0, len(wellKnownTimestamps))
+	for format := range wellKnownTimestamps {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return strings.Join(formats, ", ")
+}
@@ -0,0 +1,130 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package parsers_test
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/parsers"
+)
+
+func TestTimestampsExtractWellKnownFormats(t *testing.T) {
+	received := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		format string
+		msg    string
+		atWant time.Time
+	}{
+		{
+			parsers.TimestampRFC3339,
+			"2018-07-01T12:30:00.5+02:00 level=info",
+			time.Date(2018, 7, 1, 10, 30, 0, 5e8, time.UTC),
+		},
+		{
+			parsers.TimestampSyslog,
+			"Jul  1 12:30:00 host sshd[42]: accepted",
+			time.Date(2018, 7, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			parsers.TimestampApache,
+			`127.0.0.1 - - [01/Jul/2018:12:30:00 +0000] "GET / HTTP/1.1" 200 2`,
+			time.Date(2018, 7, 1, 12, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, c := range cases {
+		// given
+		var cons SliceConsumer
+		ts, err := parsers.WellKnownTimestamps(c.format, &cons)
+		assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+		// when
+		err = ts.On(munch.Event{At: received, Message: c.msg})
+
+		// then
+		assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+		assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+		evt := cons.Event(0)
+		assert.That(evt.At.Equal(c.atWant), t.Errorf, "%s: got event at %v, want %v", c.format, evt.At, c.atWant)
+		assert.That(evt.Message == c.msg, t.Errorf, "got event message %q, want %q", evt.Message, c.msg)
+	}
+}
+
+func TestTimestampsFallBackToTheReceivedTime(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	pattern := regexp.MustCompile(`^\[(.*?)\]`)
+	ts := parsers.NewTimestamps(pattern, []string{"2006-01-02"}, &cons)
+	received := time.Unix(5, 0)
+
+	for _, msg := range []string{"no timestamp", "[not a date] message"} {
+		// when
+		err := ts.On(munch.Event{At: received, Message: msg})
+
+		// then
+		assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	}
+	assert.That(cons.Len() == 2, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 2)
+	for i := 0; i < cons.Len(); i++ {
+		evt := cons.Event(i)
+		assert.That(evt.At.Equal(received), t.Errorf, "got event %d at %v, want %v", i, evt.At, received)
+	}
+}
+
+func TestTimestampsTryLayoutsInOrder(t *testing.T) {
+	// given
+	var cons SliceConsumer
+	pattern := regexp.MustCompile(`^\[(.*?)\]`)
+	ts := parsers.NewTimestamps(pattern, []string{"2006-01-02", "02.01.2006"}, &cons)
+	atWant := time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	// when
+	err := ts.On(munch.Event{At: time.Unix(0, 0).UTC(), Message: "[01.07.2018] message"})
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.At.Equal(atWant), t.Errorf, "got event at %v, want %v", evt.At, atWant)
+}
+
+func TestFactoryBuildsParserExtractingTimestamps(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+	atWant := time.Date(2018, 7, 1, 12, 30, 0, 0, time.UTC)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{"kind": "lines", "timestamp": {"format": "rfc3339"}}`))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	io.WriteString(parser, "2018-07-01T12:30:00Z started\n")
+
+	// then
+	assert.That(cons.Len() == 1, t.Fatalf, "got %d events submitted, want %d", cons.Len(), 1)
+	evt := cons.Event(0)
+	assert.That(evt.At.Equal(atWant), t.Errorf, "got event at %v, want %v", evt.At, atWant)
+}
+
+func TestFactoryRejectsUnknownTimestampFormat(t *testing.T) {
+	// given
+	clock := stepClock(time.Unix(0, 0), time.Second)
+	var cons SliceConsumer
+	fact := parsers.NewFactory(clock, &cons)
+
+	// when
+	parser, err := fact.NewParser("src", json.RawMessage(`{"kind": "lines", "timestamp": {"format": "mayan"}}`))
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+	assert.That(parser == nil, t.Errorf, "got parser %#v, want %#v", parser, nil)
+}
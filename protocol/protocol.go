@@ -0,0 +1,98 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package protocol lists the messages munch exchanges with its clients. Each
+// message is a JSON object with a single key, the message's tag, holding the
+// message itself.
+//
+// The server sends events read from the sources:
+//
+//	{"event/v1": {"source": "app", "at": "2019-03-01T12:00:00Z", "message": "GET /", "fields": {"status": "200"}}}
+//
+// It also reports sources failing. The kind is "input", "parser" or "exit".
+// Alive is true when the source is going to be restarted:
+//
+//	{"error/v1": {"source": "app", "kind": "input", "message": "open app.log: no such file or directory", "at": "2019-03-01T12:00:00Z", "alive": true}}
+//
+// and false when it is not, as it was stopped while waiting to be restarted:
+//
+//	{"error/v1": {"source": "app", "kind": "input", "message": "open app.log: no such file or directory", "at": "2019-03-01T12:00:00Z", "alive": false}}
+//
+// A source that read all of its input has not failed, so it only gets the ended
+// status.
+//
+// When a source changes its state, the server sends its status. The state is
+// "running", "failed", "ended", "paused" or "removed". A failed source gets
+// restarted at retryAt:
+//
+//	{"status/v1": {"source": "app", "state": "failed", "since": "2019-03-01T12:00:00Z", "error": "cannot read", "retryAt": "2019-03-01T12:00:02Z", "restarts": 3}}
+//
+// Clients can send subscriptions, to only get the events matching them:
+//
+//	{"subscribe/v1": {"sources": ["app"], "contains": "GET", "match": "", "fields": {"status": "200"}}}
+//
+// When the server allows it, clients can also add sources, with the same
+// definitions as in the config file:
+//
+//	{"add-source/v1": {"name": "app", "input": {"path": "app.log", "follow": true}, "parser": {"kind": "lines"}}}
+//
+// and remove, pause or resume them by name:
+//
+//	{"remove-source/v1": {"name": "app"}}
+//	{"pause-source/v1": {"name": "app"}}
+//	{"resume-source/v1": {"name": "app"}}
+//
+// A client can give any message an ID, next to the tag:
+//
+//	{"id": "42", "pause-source/v1": {"name": "app"}}
+//
+// Every message a client sends gets exactly one reply, with the same ID. It
+// is either an acknowledgement that the message was handled:
+//
+//	{"ack/v1": {"id": "42"}}
+//
+// or a rejection. The code is "invalid-message", "unknown-tag",
+// "unsupported-version", "not-handled" or "failed". The ID is empty when the
+// message was too broken to tell:
+//
+//	{"reject/v1": {"id": "42", "code": "failed", "message": "there is no source named \"app\""}}
+package protocol
+
+import (
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/control"
+	"github.com/szabba/munch/filters"
+	"github.com/szabba/munch/handlers"
+	"github.com/szabba/munch/tagjson"
+)
+
+var (
+	Event      = tagjson.TypeTag{Name: "event", Version: 1}
+	ErrorEvent = tagjson.TypeTag{Name: "error", Version: 1}
+	Status     = tagjson.TypeTag{Name: "status", Version: 1}
+	Subscribe  = tagjson.TypeTag{Name: "subscribe", Version: 1}
+	Ack        = tagjson.TypeTag{Name: "ack", Version: 1}
+	Reject     = tagjson.TypeTag{Name: "reject", Version: 1}
+
+	AddSource    = tagjson.TypeTag{Name: "add-source", Version: 1}
+	RemoveSource = tagjson.TypeTag{Name: "remove-source", Version: 1}
+	PauseSource  = tagjson.TypeTag{Name: "pause-source", Version: 1}
+	ResumeSource = tagjson.TypeTag{Name: "resume-source", Version: 1}
+)
+
+// NewRegistry creates a registry of all the messages in the protocol.
+func NewRegistry() *tagjson.Registry {
+	reg := tagjson.NewRegistry()
+	reg.Register(Event, munch.Event{})
+	reg.Register(ErrorEvent, munch.ErrorEvent{})
+	reg.Register(Status, munch.SourceStatus{})
+	reg.Register(Subscribe, filters.Subscribe{})
+	reg.Register(Ack, handlers.Ack{})
+	reg.Register(Reject, handlers.Reject{})
+	reg.Register(AddSource, control.AddSource{})
+	reg.Register(RemoveSource, control.RemoveSource{})
+	reg.Register(PauseSource, control.PauseSource{})
+	reg.Register(ResumeSource, control.ResumeSource{})
+	return reg
+}
@@ -0,0 +1,185 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package protocol_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/control"
+	"github.com/szabba/munch/filters"
+	"github.com/szabba/munch/handlers"
+	"github.com/szabba/munch/protocol"
+)
+
+// The wire shapes of messages must not change without a version bump.
+
+func TestEventWireShape(t *testing.T) {
+	// given
+	reg := protocol.NewRegistry()
+	evt := munch.Event{
+		Source:  "app",
+		At:      time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		Message: "GET /",
+		Fields:  map[string]string{"status": "200"},
+	}
+	want := `{"event/v1":{"source":"app","at":"2019-03-01T12:00:00Z","message":"GET /","fields":{"status":"200"}}}`
+
+	// when
+	out, err := reg.Marshal(evt)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(string(out) == want, t.Errorf, "got encoding %s, want %s", out, want)
+}
+
+func TestErrorEventWireShape(t *testing.T) {
+	// given
+	reg := protocol.NewRegistry()
+	evt := munch.ErrorEvent{
+		Source:  "app",
+		Kind:    munch.ErrorKindInput,
+		Message: "cannot read",
+		At:      time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		Alive:   true,
+	}
+	want := `{"error/v1":{"source":"app","kind":"input","message":"cannot read","at":"2019-03-01T12:00:00Z","alive":true}}`
+
+	// when
+	out, err := reg.Marshal(evt)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(string(out) == want, t.Errorf, "got encoding %s, want %s", out, want)
+}
+
+func TestDeadErrorEventWireShape(t *testing.T) {
+	// given
+	reg := protocol.NewRegistry()
+	evt := munch.ErrorEvent{
+		Source:  "app",
+		Kind:    munch.ErrorKindInput,
+		Message: "cannot read",
+		At:      time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	want := `{"error/v1":{"source":"app","kind":"input","message":"cannot read","at":"2019-03-01T12:00:00Z","alive":false}}`
+
+	// when
+	out, err := reg.Marshal(evt)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(string(out) == want, t.Errorf, "got encoding %s, want %s", out, want)
+}
+
+func TestStatusWireShape(t *testing.T) {
+	// given
+	reg := protocol.NewRegistry()
+	retryAt := time.Date(2019, 3, 1, 12, 0, 2, 0, time.UTC)
+	status := munch.SourceStatus{
+		Source:   "app",
+		State:    munch.SourceFailed,
+		Since:    time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		Error:    "cannot read",
+		RetryAt:  &retryAt,
+		Restarts: 3,
+	}
+	want := `{"status/v1":{"source":"app","state":"failed","since":"2019-03-01T12:00:00Z","error":"cannot read","retryAt":"2019-03-01T12:00:02Z","restarts":3}}`
+
+	// when
+	out, err := reg.Marshal(status)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(string(out) == want, t.Errorf, "got encoding %s, want %s", out, want)
+}
+
+func TestSubscribeWireShape(t *testing.T) {
+	// given
+	reg := protocol.NewRegistry()
+	in := `{"subscribe/v1": {"sources": ["app"], "contains": "GET", "match": "^G", "fields": {"status": "200"}}}`
+	want := filters.Subscribe{
+		Sources:  []string{"app"},
+		Contains: "GET",
+		Match:    "^G",
+		Fields:   map[string]string{"status": "200"},
+	}
+
+	// when
+	msg, err := reg.Unmarshal([]byte(in))
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(reflect.DeepEqual(msg, want), t.Errorf, "got message %#v, want %#v", msg, want)
+}
+
+func TestAckWireShape(t *testing.T) {
+	// given
+	reg := protocol.NewRegistry()
+	want := `{"ack/v1":{"id":"42"}}`
+
+	// when
+	out, err := reg.Marshal(handlers.Ack{ID: "42"})
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(string(out) == want, t.Errorf, "got encoding %s, want %s", out, want)
+}
+
+func TestRejectWireShape(t *testing.T) {
+	// given
+	reg := protocol.NewRegistry()
+	reject := handlers.Reject{ID: "42", Code: handlers.RejectUnknownTag, Message: "unknown message tag nope/v1"}
+	want := `{"reject/v1":{"id":"42","code":"unknown-tag","message":"unknown message tag nope/v1"}}`
+
+	// when
+	out, err := reg.Marshal(reject)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(string(out) == want, t.Errorf, "got encoding %s, want %s", out, want)
+}
+
+func TestAddSourceWireShape(t *testing.T) {
+	// given
+	reg := protocol.NewRegistry()
+	in := `{"add-source/v1": {"name": "app", "input": {"path": "app.log"}, "parser": {"kind": "lines"}}}`
+	want := control.AddSource{
+		Name:            "app",
+		InputDefinition: json.RawMessage(`{"path": "app.log"}`),
+		ParserDefition:  json.RawMessage(`{"kind": "lines"}`),
+	}
+
+	// when
+	msg, err := reg.Unmarshal([]byte(in))
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(reflect.DeepEqual(msg, want), t.Errorf, "got message %#v, want %#v", msg, want)
+}
+
+func TestSourceCommandWireShapes(t *testing.T) {
+	// given
+	reg := protocol.NewRegistry()
+	cases := map[string]interface{}{
+		`{"remove-source/v1": {"name": "app"}}`: control.RemoveSource{Name: "app"},
+		`{"pause-source/v1": {"name": "app"}}`:  control.PauseSource{Name: "app"},
+		`{"resume-source/v1": {"name": "app"}}`: control.ResumeSource{Name: "app"},
+	}
+
+	for in, want := range cases {
+		// when
+		msg, err := reg.Unmarshal([]byte(in))
+
+		// then
+		assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+		assert.That(reflect.DeepEqual(msg, want), t.Errorf, "got message %#v, want %#v", msg, want)
+	}
+}
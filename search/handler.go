@@ -0,0 +1,60 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package search
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/szabba/munch/filters"
+	"github.com/szabba/munch/handlers"
+)
+
+// A Handler searches a window for the events described by the URL query, as
+// read by handlers.ParsePageQuery. The response is a page of events written by
+// handlers.WritePage.
+type Handler struct {
+	win  *Window
+	fmtr handlers.MessageFormatter
+}
+
+func NewHandler(win *Window, fmtr handlers.MessageFormatter) Handler {
+	return Handler{win, fmtr}
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q, err := parseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := h.win.Find(q)
+	handlers.WritePage(w, h.fmtr, page.Events, page.Next.String())
+}
+
+func parseQuery(vals url.Values) (Query, error) {
+	pq, err := handlers.ParsePageQuery(vals)
+	if err != nil {
+		return Query{}, err
+	}
+
+	spec := filters.Spec{Sources: pq.Sources, Contains: pq.Contains}
+	filter, err := spec.Compile()
+	if err != nil {
+		return Query{}, err
+	}
+
+	after, err := ParseCursor(pq.Cursor)
+	if err != nil {
+		return Query{}, fmt.Errorf("invalid cursor %q", pq.Cursor)
+	}
+	return Query{Filter: filter, Since: pq.Since, Until: pq.Until, After: after, Limit: pq.Limit}, nil
+}
@@ -0,0 +1,89 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package search_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/search"
+)
+
+func TestHandlerReturnsFormattedEventsWithCursor(t *testing.T) {
+	// given
+	win := search.NewWindow(10)
+	fill(win, "app", "first", "second", "third")
+	fill(win, "db", "other")
+	h := search.NewHandler(win, MessageFormatter{})
+
+	// when
+	resp := get(t, h, "/events?source=app&q=ir&limit=1")
+
+	// then
+	assert.That(resp.Code == http.StatusOK, t.Fatalf, "got status %d, want %d", resp.Code, http.StatusOK)
+	var out struct {
+		Events []string `json:"events"`
+		Next   string   `json:"next"`
+	}
+	err := json.Unmarshal(resp.Body.Bytes(), &out)
+	assert.That(err == nil, t.Fatalf, "cannot decode response %q: %s", resp.Body, err)
+	assert.That(len(out.Events) == 1 && out.Events[0] == "third", t.Errorf, "got events %q, want %q", out.Events, []string{"third"})
+	assert.That(out.Next != "", t.Errorf, "got no next cursor")
+
+	// when
+	resp = get(t, h, "/events?source=app&q=ir&limit=1&cursor="+out.Next)
+
+	// then
+	out.Next = ""
+	err = json.Unmarshal(resp.Body.Bytes(), &out)
+	assert.That(err == nil, t.Fatalf, "cannot decode response %q: %s", resp.Body, err)
+	assert.That(len(out.Events) == 1 && out.Events[0] == "first", t.Errorf, "got events %q, want %q", out.Events, []string{"first"})
+	assert.That(out.Next == "", t.Errorf, "got next cursor %q, want none", out.Next)
+}
+
+func TestHandlerRejectsInvalidQueries(t *testing.T) {
+	queries := []string{
+		"since=yesterday",
+		"until=1",
+		"limit=0",
+		"limit=many",
+		"cursor=-1",
+	}
+	for _, q := range queries {
+		// given
+		h := search.NewHandler(search.NewWindow(10), MessageFormatter{})
+
+		// when
+		resp := get(t, h, "/events?"+q)
+
+		// then
+		assert.That(resp.Code == http.StatusBadRequest, t.Errorf, "%s: got status %d, want %d", q, resp.Code, http.StatusBadRequest)
+	}
+}
+
+func get(t *testing.T, h http.Handler, url string) *httptest.ResponseRecorder {
+	t.Helper()
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, url, nil))
+	return resp
+}
+
+// A MessageFormatter writes out the messages of events as JSON strings.
+type MessageFormatter struct{}
+
+func (MessageFormatter) FormatMessage(w io.Writer, msg interface{}) error {
+	evt, ok := msg.(munch.Event)
+	if !ok {
+		return fmt.Errorf("unexpected message %#v", msg)
+	}
+	return json.NewEncoder(w).Encode(evt.Message)
+}
@@ -0,0 +1,128 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package search
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/filters"
+	"github.com/szabba/munch/parsers"
+)
+
+// A Cursor marks where a page of results ended. The zero cursor stands for
+// the start of the results.
+type Cursor uint64
+
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return 0, nil
+	}
+	c, err := strconv.ParseUint(s, 10, 64)
+	return Cursor(c), err
+}
+
+func (c Cursor) String() string {
+	if c == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(c), 10)
+}
+
+// A Query selects events from a window. A nil Filter and zero times match all
+// events. Since is inclusive and Until exclusive. After continues from where
+// an earlier page ended.
+type Query struct {
+	Filter *filters.Filter
+	Since  time.Time
+	Until  time.Time
+	After  Cursor
+	Limit  int
+}
+
+func (q Query) matches(evt munch.Event) bool {
+	if q.Filter != nil && !q.Filter.Matches(evt) {
+		return false
+	}
+	if !q.Since.IsZero() && evt.At.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !evt.At.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// A Page holds events matching a query. When there are more, Next is the
+// cursor to continue from.
+type Page struct {
+	Events []munch.Event
+	Next   Cursor
+}
+
+// A Window keeps a bounded number of the most recently received events.
+type Window struct {
+	lock   sync.Mutex
+	size   int
+	seq    uint64
+	events []windowEvent
+}
+
+type windowEvent struct {
+	seq uint64
+	evt munch.Event
+}
+
+var _ parsers.EventConsumer = new(Window)
+
+func NewWindow(size int) *Window {
+	return &Window{size: size}
+}
+
+func (w *Window) On(evt munch.Event) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.size <= 0 {
+		return nil
+	}
+	w.seq++
+	if len(w.events) == w.size {
+		w.events[0] = windowEvent{}
+		w.events = w.events[1:]
+	}
+	w.events = append(w.events, windowEvent{w.seq, evt})
+	return nil
+}
+
+// Find returns the events matching the query, the most recently received
+// first. A page has at most q.Limit events, unless the limit is not positive.
+func (w *Window) Find(q Query) Page {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var (
+		page Page
+		last uint64
+	)
+	for i := len(w.events) - 1; i >= 0; i-- {
+		wevt := w.events[i]
+		if q.After != 0 && wevt.seq >= uint64(q.After) {
+			continue
+		}
+		if !q.matches(wevt.evt) {
+			continue
+		}
+		if q.Limit > 0 && len(page.Events) == q.Limit {
+			page.Next = Cursor(last)
+			break
+		}
+		page.Events = append(page.Events, wevt.evt)
+		last = wevt.seq
+	}
+	return page
+}
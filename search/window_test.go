@@ -0,0 +1,122 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/filters"
+	"github.com/szabba/munch/search"
+)
+
+var Start = time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestWindowFindsNewestEventsFirst(t *testing.T) {
+	// given
+	win := search.NewWindow(10)
+	fill(win, "app", "first", "second", "third")
+
+	// when
+	page := win.Find(search.Query{})
+
+	// then
+	assertMessages(t, page, "third", "second", "first")
+	assert.That(page.Next == 0, t.Errorf, "got next cursor %s, want none", page.Next)
+}
+
+func TestWindowForgetsOldestEvents(t *testing.T) {
+	// given
+	win := search.NewWindow(2)
+
+	// when
+	fill(win, "app", "first", "second", "third")
+
+	// then
+	assertMessages(t, win.Find(search.Query{}), "third", "second")
+}
+
+func TestWindowFindsEventsMatchingFilter(t *testing.T) {
+	// given
+	win := search.NewWindow(10)
+	fill(win, "app", "GET /", "POST /login")
+	fill(win, "db", "GET /")
+	filter, err := filters.Spec{Sources: []string{"app"}, Contains: "GET"}.Compile()
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	page := win.Find(search.Query{Filter: filter})
+
+	// then
+	assertMessages(t, page, "GET /")
+}
+
+func TestWindowFindsEventsInTimeRange(t *testing.T) {
+	// given
+	win := search.NewWindow(10)
+	fill(win, "app", "0", "1", "2", "3")
+
+	// when
+	page := win.Find(search.Query{Since: Start.Add(time.Minute), Until: Start.Add(3 * time.Minute)})
+
+	// then
+	assertMessages(t, page, "2", "1")
+}
+
+func TestWindowPaginatesWithCursor(t *testing.T) {
+	// given
+	win := search.NewWindow(10)
+	fill(win, "app", "0", "skip", "1", "2", "skip", "3", "4")
+	filter, _ := filters.Spec{Match: `^\d$`}.Compile()
+
+	// when
+	first := win.Find(search.Query{Filter: filter, Limit: 2})
+	second := win.Find(search.Query{Filter: filter, Limit: 2, After: first.Next})
+	third := win.Find(search.Query{Filter: filter, Limit: 2, After: second.Next})
+
+	// then
+	assertMessages(t, first, "4", "3")
+	assertMessages(t, second, "2", "1")
+	assertMessages(t, third, "0")
+	assert.That(first.Next != 0, t.Errorf, "first page has no next cursor")
+	assert.That(third.Next == 0, t.Errorf, "got next cursor %s after last page, want none", third.Next)
+}
+
+func TestWindowCursorSurvivesNewEvents(t *testing.T) {
+	// given
+	win := search.NewWindow(10)
+	fill(win, "app", "0", "1", "2")
+	first := win.Find(search.Query{Limit: 2})
+
+	// when
+	fill(win, "app", "3")
+	second := win.Find(search.Query{Limit: 2, After: first.Next})
+
+	// then
+	assertMessages(t, second, "0")
+}
+
+// fill adds events with the messages, a minute apart from Start.
+func fill(win *search.Window, source string, msgs ...string) {
+	for i, msg := range msgs {
+		win.On(munch.Event{Source: source, At: Start.Add(time.Duration(i) * time.Minute), Message: msg})
+	}
+}
+
+func assertMessages(t *testing.T, page search.Page, want ...string) {
+	t.Helper()
+
+	got := make([]string, len(page.Events))
+	for i, evt := range page.Events {
+		got[i] = evt.Message
+	}
+	assert.That(len(got) == len(want), t.Fatalf, "got messages %q, want %q", got, want)
+	for i := range want {
+		assert.That(got[i] == want[i], t.Errorf, "got messages %q, want %q", got, want)
+	}
+}
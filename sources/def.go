@@ -6,9 +6,25 @@ package sources
 
 import (
 	"encoding/json"
+	"fmt"
 )
 
 type Definition struct {
-	InputDefinition json.RawMessage
-	ParserDefition  json.RawMessage
+	Name            string          `json:"name"`
+	InputDefinition json.RawMessage `json:"input"`
+	ParserDefition  json.RawMessage `json:"parser"`
+}
+
+// Validate checks that the definition has a name and both an input and
+// a parser definition. It does not look into the latter two.
+func (def Definition) Validate() error {
+	switch {
+	case def.Name == "":
+		return fmt.Errorf("source definition has no name")
+	case len(def.InputDefinition) == 0:
+		return fmt.Errorf("source %q has no input definition", def.Name)
+	case len(def.ParserDefition) == 0:
+		return fmt.Errorf("source %q has no parser definition", def.Name)
+	}
+	return nil
 }
@@ -0,0 +1,29 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package sources
+
+import "io"
+
+// A ParserError reports that a source failed because of its parser rather
+// than its input.
+type ParserError struct {
+	Err error
+}
+
+func (err *ParserError) Error() string {
+	return "parser failed: " + err.Err.Error()
+}
+
+type parserWriter struct {
+	parser io.Writer
+}
+
+func (pw parserWriter) Write(p []byte) (int, error) {
+	n, err := pw.parser.Write(p)
+	if err != nil {
+		err = &ParserError{err}
+	}
+	return n, err
+}
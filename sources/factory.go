@@ -14,12 +14,18 @@ type Factory struct {
 	parserFactory ParserFactory
 }
 
+// An InputFactory creates inputs out of their definitions. ValidateInput
+// checks a definition without starting anything.
 type InputFactory interface {
-	NewInput(json.RawMessage) (io.ReadCloser, error)
+	NewInput(name string, def json.RawMessage) (io.ReadCloser, error)
+	ValidateInput(name string, def json.RawMessage) error
 }
 
+// A ParserFactory creates parsers out of their definitions. ValidateParser
+// checks a definition without creating a parser.
 type ParserFactory interface {
-	NewParser(json.RawMessage) (io.WriteCloser, error)
+	NewParser(name string, def json.RawMessage) (io.WriteCloser, error)
+	ValidateParser(name string, def json.RawMessage) error
 }
 
 func NewFactory(inputFactory InputFactory, parserFactory ParserFactory) *Factory {
@@ -29,12 +35,38 @@ func NewFactory(inputFactory InputFactory, parserFactory ParserFactory) *Factory
 	}
 }
 
+// Validate checks the definition, including those of the input and the
+// parser.
+func (fact *Factory) Validate(def Definition) error {
+	err := def.Validate()
+	if err != nil {
+		return err
+	}
+	err = fact.inputFactory.ValidateInput(def.Name, def.InputDefinition)
+	if err != nil {
+		return err
+	}
+	return fact.parserFactory.ValidateParser(def.Name, def.ParserDefition)
+}
+
+// NewSource validates the definition before creating the source, so that an
+// invalid one does not get the input started.
 func (fact *Factory) NewSource(def Definition) (*Source, error) {
-	input, err := fact.inputFactory.NewInput(def.InputDefinition)
+	err := fact.Validate(def)
 	if err != nil {
 		return nil, err
 	}
-	parser, err := fact.parserFactory.NewParser(def.ParserDefition)
+	input, err := fact.inputFactory.NewInput(def.Name, def.InputDefinition)
+	if err != nil {
+		return nil, err
+	}
+	if fanout, ok := input.(Fanout); ok {
+		newParser := func(name string) (io.WriteCloser, error) {
+			return fact.parserFactory.NewParser(name, def.ParserDefition)
+		}
+		return NewFanout(fanout, newParser), nil
+	}
+	parser, err := fact.parserFactory.NewParser(def.Name, def.ParserDefition)
 	if err != nil {
 		input.Close()
 		return nil, err
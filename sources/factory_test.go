@@ -26,13 +26,15 @@ func TestFactoryFailsToCreateSourceIfAnInputCannotBeCreated(t *testing.T) {
 	factory := sources.NewFactory(inputFactory, parserFactory)
 
 	def := sources.Definition{
+		Name:            "src",
 		InputDefinition: json.RawMessage(`{}`),
 		ParserDefition:  json.RawMessage(`null`),
 	}
+	expectValidDefinition(inputFactory, parserFactory, def)
 
 	errWant := errors.New("cannot create input")
 
-	inputFactory.EXPECT().NewInput(def.InputDefinition).Return(nil, errWant)
+	inputFactory.EXPECT().NewInput(def.Name, def.InputDefinition).Return(nil, errWant)
 
 	// when
 	src, err := factory.NewSource(def)
@@ -53,17 +55,19 @@ func TestFactoryFailsToCreateSourceIfAParserCannotBeCreated(t *testing.T) {
 	factory := sources.NewFactory(inputFactory, parserFactory)
 
 	def := sources.Definition{
+		Name:            "src",
 		InputDefinition: json.RawMessage(`{}`),
 		ParserDefition:  json.RawMessage(`null`),
 	}
+	expectValidDefinition(inputFactory, parserFactory, def)
 
 	errWant := errors.New("cannot create parser")
 
 	input := NewMockReadCloser(ctrl)
 
 	gomock.InOrder(
-		inputFactory.EXPECT().NewInput(def.InputDefinition).Return(input, nil),
-		parserFactory.EXPECT().NewParser(def.ParserDefition).Return(nil, errWant),
+		inputFactory.EXPECT().NewInput(def.Name, def.InputDefinition).Return(input, nil),
+		parserFactory.EXPECT().NewParser(def.Name, def.ParserDefition).Return(nil, errWant),
 		input.EXPECT().Close())
 
 	// when
@@ -85,16 +89,18 @@ func TestFactoryCreatesSourceIfBothInputAndParserCanBeCreated(t *testing.T) {
 	factory := sources.NewFactory(inputFactory, parserFactory)
 
 	def := sources.Definition{
+		Name:            "src",
 		InputDefinition: json.RawMessage(`{}`),
 		ParserDefition:  json.RawMessage(`null`),
 	}
+	expectValidDefinition(inputFactory, parserFactory, def)
 
 	input := NewMockReadCloser(ctrl)
 	parser := NewMockWriteCloser(ctrl)
 
 	gomock.InOrder(
-		inputFactory.EXPECT().NewInput(def.InputDefinition).Return(input, nil),
-		parserFactory.EXPECT().NewParser(def.ParserDefition).Return(parser, nil))
+		inputFactory.EXPECT().NewInput(def.Name, def.InputDefinition).Return(input, nil),
+		parserFactory.EXPECT().NewParser(def.Name, def.ParserDefition).Return(parser, nil))
 
 	// when
 	src, err := factory.NewSource(def)
@@ -103,3 +109,56 @@ func TestFactoryCreatesSourceIfBothInputAndParserCanBeCreated(t *testing.T) {
 	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
 	assert.That(src != nil, t.Errorf, "got source %#v, want %#v", src, nil)
 }
+
+func TestFactoryDoesNotCreateInputForInvalidDefinition(t *testing.T) {
+	// given
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	inputFactory := NewMockInputFactory(ctrl)
+	parserFactory := NewMockParserFactory(ctrl)
+
+	factory := sources.NewFactory(inputFactory, parserFactory)
+
+	def := sources.Definition{
+		Name:            "src",
+		InputDefinition: json.RawMessage(`{}`),
+		ParserDefition:  json.RawMessage(`null`),
+	}
+
+	errWant := errors.New("invalid parser definition")
+
+	inputFactory.EXPECT().ValidateInput(def.Name, def.InputDefinition).Return(nil)
+	parserFactory.EXPECT().ValidateParser(def.Name, def.ParserDefition).Return(errWant)
+
+	// when
+	src, err := factory.NewSource(def)
+
+	// then
+	assert.That(err == errWant, t.Errorf, "got error %q, want %q", err, errWant)
+	assert.That(src == nil, t.Errorf, "got source %#v, want %#v", src, nil)
+}
+
+func TestFactoryRejectsDefinitionWithoutName(t *testing.T) {
+	// given
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	factory := sources.NewFactory(NewMockInputFactory(ctrl), NewMockParserFactory(ctrl))
+
+	def := sources.Definition{
+		InputDefinition: json.RawMessage(`{}`),
+		ParserDefition:  json.RawMessage(`null`),
+	}
+
+	// when
+	err := factory.Validate(def)
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+}
+
+func expectValidDefinition(inputFactory *MockInputFactory, parserFactory *MockParserFactory, def sources.Definition) {
+	inputFactory.EXPECT().ValidateInput(def.Name, def.InputDefinition).Return(nil)
+	parserFactory.EXPECT().ValidateParser(def.Name, def.ParserDefition).Return(nil)
+}
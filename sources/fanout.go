@@ -0,0 +1,28 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package sources
+
+import (
+	"io"
+)
+
+// A Stream is one of the inputs a Fanout splits into. Name identifies it in
+// the events it produces.
+type Stream struct {
+	Name  string
+	Input io.ReadCloser
+}
+
+// A Fanout is an input made up of many streams, each of which gets a parser
+// of its own. Streams get sent as they appear and the channel is closed once
+// no more will come.
+//
+// A Fanout carries no data of its own. Reading it blocks until it either fails
+// or stops producing streams. Streams produced before a failure still get
+// processed until they end, and only then is the failure reported.
+type Fanout interface {
+	io.ReadCloser
+	Streams() <-chan Stream
+}
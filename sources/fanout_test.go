@@ -0,0 +1,134 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package sources_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/sources"
+)
+
+func TestFactoryCreatesAParserForEveryStreamOfAFanout(t *testing.T) {
+	// given
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	inputFactory := NewMockInputFactory(ctrl)
+	parserFactory := NewMockParserFactory(ctrl)
+
+	factory := sources.NewFactory(inputFactory, parserFactory)
+
+	def := sources.Definition{
+		Name:            "src",
+		InputDefinition: json.RawMessage(`{}`),
+		ParserDefition:  json.RawMessage(`null`),
+	}
+	expectValidDefinition(inputFactory, parserFactory, def)
+
+	fanout := NewSliceFanout(
+		sources.Stream{Name: "a", Input: ioutil.NopCloser(strings.NewReader("from a"))},
+		sources.Stream{Name: "b", Input: ioutil.NopCloser(strings.NewReader("from b"))})
+
+	var a, b BufferParser
+
+	inputFactory.EXPECT().NewInput(def.Name, def.InputDefinition).Return(fanout, nil)
+	parserFactory.EXPECT().NewParser("a", def.ParserDefition).Return(&a, nil)
+	parserFactory.EXPECT().NewParser("b", def.ParserDefition).Return(&b, nil)
+
+	src, err := factory.NewSource(def)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	err = src.Process()
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(a.String() == "from a", t.Errorf, "got %q into parser a, want %q", a.String(), "from a")
+	assert.That(b.String() == "from b", t.Errorf, "got %q into parser b, want %q", b.String(), "from b")
+}
+
+func TestFanoutSourceFailsWhenAParserCannotBeCreated(t *testing.T) {
+	// given
+	fanout := NewSliceFanout(
+		sources.Stream{Name: "a", Input: ioutil.NopCloser(strings.NewReader("from a"))})
+
+	errWant := errors.New("cannot create parser")
+	src := sources.NewFanout(fanout, func(string) (io.WriteCloser, error) { return nil, errWant })
+
+	// when
+	err := src.Process()
+
+	// then
+	parserErr, ok := err.(*sources.ParserError)
+	assert.That(ok && parserErr.Err == errWant, t.Errorf, "got error %#v, want a parser error wrapping %q", err, errWant)
+	assert.That(fanout.Closed(), t.Errorf, "fanout was not closed")
+}
+
+type SliceFanout struct {
+	once    sync.Once
+	streams chan sources.Stream
+	closed  chan struct{}
+}
+
+var _ sources.Fanout = new(SliceFanout)
+
+func NewSliceFanout(streams ...sources.Stream) *SliceFanout {
+	fanout := &SliceFanout{
+		streams: make(chan sources.Stream, len(streams)),
+		closed:  make(chan struct{}),
+	}
+	for _, stream := range streams {
+		fanout.streams <- stream
+	}
+	close(fanout.streams)
+	return fanout
+}
+
+func (f *SliceFanout) Streams() <-chan sources.Stream { return f.streams }
+
+func (f *SliceFanout) Read(p []byte) (int, error) { return 0, io.EOF }
+
+func (f *SliceFanout) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return nil
+}
+
+func (f *SliceFanout) Closed() bool {
+	select {
+	case <-f.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+type BufferParser struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (p *BufferParser) Write(data []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.buf.Write(data)
+}
+
+func (p *BufferParser) Close() error { return nil }
+
+func (p *BufferParser) String() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.buf.String()
+}
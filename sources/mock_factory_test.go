@@ -35,16 +35,28 @@ func (m *MockInputFactory) EXPECT() *MockInputFactoryMockRecorder {
 }
 
 // NewInput mocks base method
-func (m *MockInputFactory) NewInput(arg0 json.RawMessage) (io.ReadCloser, error) {
-	ret := m.ctrl.Call(m, "NewInput", arg0)
+func (m *MockInputFactory) NewInput(arg0 string, arg1 json.RawMessage) (io.ReadCloser, error) {
+	ret := m.ctrl.Call(m, "NewInput", arg0, arg1)
 	ret0, _ := ret[0].(io.ReadCloser)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // NewInput indicates an expected call of NewInput
-func (mr *MockInputFactoryMockRecorder) NewInput(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewInput", reflect.TypeOf((*MockInputFactory)(nil).NewInput), arg0)
+func (mr *MockInputFactoryMockRecorder) NewInput(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewInput", reflect.TypeOf((*MockInputFactory)(nil).NewInput), arg0, arg1)
+}
+
+// ValidateInput mocks base method
+func (m *MockInputFactory) ValidateInput(arg0 string, arg1 json.RawMessage) error {
+	ret := m.ctrl.Call(m, "ValidateInput", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateInput indicates an expected call of ValidateInput
+func (mr *MockInputFactoryMockRecorder) ValidateInput(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateInput", reflect.TypeOf((*MockInputFactory)(nil).ValidateInput), arg0, arg1)
 }
 
 // MockParserFactory is a mock of ParserFactory interface
@@ -71,14 +83,26 @@ func (m *MockParserFactory) EXPECT() *MockParserFactoryMockRecorder {
 }
 
 // NewParser mocks base method
-func (m *MockParserFactory) NewParser(arg0 json.RawMessage) (io.WriteCloser, error) {
-	ret := m.ctrl.Call(m, "NewParser", arg0)
+func (m *MockParserFactory) NewParser(arg0 string, arg1 json.RawMessage) (io.WriteCloser, error) {
+	ret := m.ctrl.Call(m, "NewParser", arg0, arg1)
 	ret0, _ := ret[0].(io.WriteCloser)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // NewParser indicates an expected call of NewParser
-func (mr *MockParserFactoryMockRecorder) NewParser(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewParser", reflect.TypeOf((*MockParserFactory)(nil).NewParser), arg0)
+func (mr *MockParserFactoryMockRecorder) NewParser(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewParser", reflect.TypeOf((*MockParserFactory)(nil).NewParser), arg0, arg1)
+}
+
+// ValidateParser mocks base method
+func (m *MockParserFactory) ValidateParser(arg0 string, arg1 json.RawMessage) error {
+	ret := m.ctrl.Call(m, "ValidateParser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateParser indicates an expected call of ValidateParser
+func (mr *MockParserFactoryMockRecorder) ValidateParser(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateParser", reflect.TypeOf((*MockParserFactory)(nil).ValidateParser), arg0, arg1)
 }
@@ -6,17 +6,25 @@ package sources
 
 import (
 	"io"
+	"io/ioutil"
 	"sync"
 
 	"github.com/oklog/run"
 )
 
 type Source struct {
-	once      sync.Once
-	input     io.ReadCloser
-	midWriter *io.PipeWriter
-	midReader *io.PipeReader
-	parser    io.WriteCloser
+	once       sync.Once
+	input      io.ReadCloser
+	midWriter  *io.PipeWriter
+	midReader  *io.PipeReader
+	parser     io.WriteCloser
+	parserOnce sync.Once
+
+	fanout    Fanout
+	newParser func(name string) (io.WriteCloser, error)
+	lock      sync.Mutex
+	stopped   bool
+	children  []*Source
 }
 
 func New(input io.ReadCloser, parser io.WriteCloser) *Source {
@@ -29,15 +37,124 @@ func New(input io.ReadCloser, parser io.WriteCloser) *Source {
 	}
 }
 
+// NewFanout creates a source processing every stream of the input with a
+// parser of its own.
+func NewFanout(input Fanout, newParser func(name string) (io.WriteCloser, error)) *Source {
+	return &Source{
+		input:     input,
+		fanout:    input,
+		newParser: newParser,
+	}
+}
+
 func (src *Source) Process() error {
+	if src.fanout != nil {
+		return src.processFanout()
+	}
+	// The input can end before the parser fails on the last of it, so the
+	// parser's failure is kept even when it is not the first result.
+	var parserErr error
 	g := new(run.Group)
 	g.Add(
 		func() error { return src.copy(src.midWriter, src.input) },
 		func(err error) { src.midWriter.CloseWithError(err) })
 	g.Add(
-		func() error { return src.copy(src.parser, src.midReader) },
-		func(err error) { src.midReader.CloseWithError(err) })
-	return g.Run()
+		func() error {
+			err := src.copy(parserWriter{src.parser}, src.midReader)
+			if err == nil {
+				// The parser can hold on to the end of the input, waiting
+				// for more of it.
+				err = src.closeParser()
+			}
+			if _, ok := err.(*ParserError); ok {
+				parserErr = err
+			}
+			return err
+		},
+		func(err error) {
+			// When the input ends, the parser still gets the rest of it.
+			if err != nil {
+				src.midReader.CloseWithError(err)
+			}
+		})
+	err := g.Run()
+	if err == nil {
+		err = parserErr
+	}
+	return err
+}
+
+func (src *Source) processFanout() error {
+	failed := make(chan error, 1)
+	var wg sync.WaitGroup
+	spawn := func(f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := f()
+			if err != nil {
+				select {
+				case failed <- err:
+				default:
+				}
+			}
+		}()
+	}
+
+	var fanoutErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fanoutErr = src.copy(ioutil.Discard, src.fanout)
+	}()
+	spawn(func() error {
+		for stream := range src.fanout.Streams() {
+			child, err := src.newChild(stream)
+			if err != nil {
+				return err
+			}
+			spawn(child.Process)
+		}
+		return nil
+	})
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case err := <-failed:
+		src.Stop()
+		<-finished
+		return err
+	case <-finished:
+	}
+	select {
+	case err := <-failed:
+		return err
+	default:
+		return fanoutErr
+	}
+}
+
+func (src *Source) newChild(stream Stream) (*Source, error) {
+	parser, err := src.newParser(stream.Name)
+	if err != nil {
+		stream.Input.Close()
+		return nil, &ParserError{err}
+	}
+	child := New(stream.Input, parser)
+
+	src.lock.Lock()
+	defer src.lock.Unlock()
+	if src.stopped {
+		child.Stop()
+	} else {
+		src.children = append(src.children, child)
+	}
+	return child, nil
 }
 
 func (src *Source) copy(w io.Writer, r io.Reader) error {
@@ -45,11 +162,33 @@ func (src *Source) copy(w io.Writer, r io.Reader) error {
 	return err
 }
 
+func (src *Source) closeParser() error {
+	var err error
+	src.parserOnce.Do(func() { err = src.parser.Close() })
+	if err != nil {
+		return &ParserError{err}
+	}
+	return nil
+}
+
 func (src *Source) Stop() {
 	src.once.Do(src.close)
 }
 
 func (src *Source) close() {
 	src.input.Close()
-	src.parser.Close()
+	if src.fanout == nil {
+		src.closeParser()
+		return
+	}
+
+	src.lock.Lock()
+	src.stopped = true
+	children := src.children
+	src.children = nil
+	src.lock.Unlock()
+
+	for _, child := range children {
+		child.Stop()
+	}
 }
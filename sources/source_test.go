@@ -6,8 +6,11 @@ package sources_test
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -134,3 +137,88 @@ func TestSourceClosesUpWhenTheParserRefusesFurtherInput(t *testing.T) {
 	// then
 	assert.That(n == 0, t.Errorf, "wrote %d bytes, expected %d", n, 0)
 }
+
+func TestSourceReportsParserFailure(t *testing.T) {
+	// given
+	in := ioutil.NopCloser(strings.NewReader("some input"))
+	errWant := errors.New("cannot parse")
+	src := sources.New(in, FailingParser{errWant})
+
+	// when
+	err := src.Process()
+
+	// then
+	parserErr, ok := err.(*sources.ParserError)
+	assert.That(ok && parserErr.Err == errWant, t.Errorf, "got error %#v, want a parser error wrapping %q", err, errWant)
+}
+
+func TestSourceReportsInputFailureAsIs(t *testing.T) {
+	// given
+	errWant := errors.New("cannot read")
+	in, inWriter := io.Pipe()
+	inWriter.CloseWithError(errWant)
+	_, out := io.Pipe()
+	src := sources.New(in, out)
+
+	// when
+	err := src.Process()
+
+	// then
+	assert.That(err == errWant, t.Errorf, "got error %#v, want %q", err, errWant)
+}
+
+type FailingParser struct {
+	err error
+}
+
+func (p FailingParser) Write([]byte) (int, error) { return 0, p.err }
+
+func (p FailingParser) Close() error { return nil }
+
+func TestSourceClosesParserWhenTheInputEnds(t *testing.T) {
+	// given
+	in := ioutil.NopCloser(strings.NewReader("abcd"))
+	parser := new(ClosingParser)
+	src := sources.New(in, parser)
+
+	// when
+	err := src.Process()
+
+	// then
+	assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	assert.That(parser.Closes() == 1, t.Errorf, "got parser closed %d times, want %d", parser.Closes(), 1)
+}
+
+func TestSourceClosesParserOnceWhenStoppedAfterTheInputEnds(t *testing.T) {
+	// given
+	in := ioutil.NopCloser(strings.NewReader("abcd"))
+	parser := new(ClosingParser)
+	src := sources.New(in, parser)
+	src.Process()
+
+	// when
+	src.Stop()
+
+	// then
+	assert.That(parser.Closes() == 1, t.Errorf, "got parser closed %d times, want %d", parser.Closes(), 1)
+}
+
+type ClosingParser struct {
+	lock   sync.Mutex
+	closes int
+}
+
+func (p *ClosingParser) Write(data []byte) (int, error) { return len(data), nil }
+
+func (p *ClosingParser) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closes++
+	return nil
+}
+
+func (p *ClosingParser) Closes() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.closes
+}
@@ -0,0 +1,29 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package munch
+
+import "time"
+
+const (
+	SourceRunning = "running"
+	SourceFailed  = "failed"
+	SourceEnded   = "ended"
+	SourcePaused  = "paused"
+	SourceRemoved = "removed"
+)
+
+// A SourceStatus reports what a source is doing since some time. A "running"
+// source is reading its input, an "ended" one has read all of it. A "failed"
+// one waits to be restarted at RetryAt, and Error says why it failed. Restarts
+// counts how many times the source was restarted. A "paused" source was
+// stopped by a client and can be resumed, a "removed" one is gone for good.
+type SourceStatus struct {
+	Source   string     `json:"source"`
+	State    string     `json:"state"`
+	Since    time.Time  `json:"since"`
+	Error    string     `json:"error,omitempty"`
+	RetryAt  *time.Time `json:"retryAt,omitempty"`
+	Restarts int        `json:"restarts"`
+}
@@ -0,0 +1,75 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package store
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/handlers"
+)
+
+// A Handler reads stored events described by the URL query, as read by
+// handlers.ParsePageQuery, oldest first. Stored events cannot be searched for
+// text, so the q parameter is ignored. The response is a page of events written
+// by handlers.WritePage.
+type Handler struct {
+	store *Store
+	fmtr  handlers.MessageFormatter
+}
+
+func NewHandler(store *Store, fmtr handlers.MessageFormatter) Handler {
+	return Handler{store, fmtr}
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q, limit, err := parseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recs, err := h.store.Read(q, limit+1)
+	if err != nil {
+		log.Printf("cannot read stored events: %s", err)
+		http.Error(w, "cannot read events", http.StatusInternalServerError)
+		return
+	}
+
+	var next string
+	if len(recs) > limit {
+		recs = recs[:limit]
+		next = strconv.FormatUint(recs[limit-1].Seq, 10)
+	}
+	evts := make([]munch.Event, 0, len(recs))
+	for _, rec := range recs {
+		evts = append(evts, rec.Event)
+	}
+	handlers.WritePage(w, h.fmtr, evts, next)
+}
+
+func parseQuery(vals url.Values) (Query, int, error) {
+	pq, err := handlers.ParsePageQuery(vals)
+	if err != nil {
+		return Query{}, 0, err
+	}
+
+	q := Query{Sources: pq.Sources, Since: pq.Since, Until: pq.Until}
+	if pq.Cursor != "" {
+		q.After, err = strconv.ParseUint(pq.Cursor, 10, 64)
+		if err != nil {
+			return Query{}, 0, fmt.Errorf("invalid cursor %q", pq.Cursor)
+		}
+	}
+	return q, pq.Limit, nil
+}
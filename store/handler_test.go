@@ -0,0 +1,69 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package store_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/store"
+)
+
+func TestHandlerPagesThroughStoredEvents(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s := openStore(t, dir, store.DefaultOptions())
+	defer s.Close()
+	appendEvents(t, s, "app", "first", "second", "third")
+	appendEvents(t, s, "db", "other")
+	h := store.NewHandler(s, MessageFormatter{})
+
+	// when
+	first := get(t, h, "/archive?source=app&limit=2")
+	second := get(t, h, "/archive?source=app&limit=2&cursor="+first.Next)
+
+	// then
+	assert.That(fmt.Sprint(first.Events) == "[first second]", t.Errorf, "got first page %q, want %q", first.Events, []string{"first", "second"})
+	assert.That(first.Next != "", t.Errorf, "got no next cursor")
+	assert.That(fmt.Sprint(second.Events) == "[third]", t.Errorf, "got second page %q, want %q", second.Events, []string{"third"})
+	assert.That(second.Next == "", t.Errorf, "got next cursor %q, want none", second.Next)
+}
+
+type page struct {
+	Events []string `json:"events"`
+	Next   string   `json:"next"`
+}
+
+func get(t *testing.T, h http.Handler, url string) page {
+	t.Helper()
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.That(resp.Code == http.StatusOK, t.Fatalf, "got status %d, want %d", resp.Code, http.StatusOK)
+
+	var out page
+	err := json.Unmarshal(resp.Body.Bytes(), &out)
+	assert.That(err == nil, t.Fatalf, "cannot decode response %q: %s", resp.Body, err)
+	return out
+}
+
+// A MessageFormatter writes out the messages of events as JSON strings.
+type MessageFormatter struct{}
+
+func (MessageFormatter) FormatMessage(w io.Writer, msg interface{}) error {
+	evt, ok := msg.(munch.Event)
+	if !ok {
+		return fmt.Errorf("unexpected message %#v", msg)
+	}
+	return json.NewEncoder(w).Encode(evt.Message)
+}
@@ -0,0 +1,196 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+// SegmentExt is the extension of segment files. A segment's name is the
+// sequence number of its first event.
+const SegmentExt = ".events"
+
+// A segment is a file of events, one JSON object per line, together with an
+// index of them.
+type segment struct {
+	path    string
+	base    uint64
+	next    uint64
+	size    int64
+	modTime time.Time
+	minAt   time.Time
+	maxAt   time.Time
+	sources map[string]int
+	entries []entry
+}
+
+type entry struct {
+	seq    uint64
+	offset int64
+	length int
+	at     time.Time
+	source string
+}
+
+func segmentPath(dir string, base uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", base, SegmentExt))
+}
+
+func parseSegmentName(name string) (uint64, bool) {
+	if !strings.HasSuffix(name, SegmentExt) {
+		return 0, false
+	}
+	base, err := strconv.ParseUint(strings.TrimSuffix(name, SegmentExt), 10, 64)
+	return base, err == nil
+}
+
+func newSegment(path string, base uint64) *segment {
+	return &segment{path: path, base: base, next: base, sources: make(map[string]int)}
+}
+
+// loadSegment rebuilds the index of the segment at path. An incomplete last
+// line is what a crash mid-write leaves behind. When repair is set, it gets
+// cut off the file, otherwise it is skipped. Lines that are not valid events
+// are skipped, but keep their sequence numbers.
+func loadSegment(path string, base uint64, repair bool) (*segment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	seg := newSegment(path, base)
+	seg.modTime = info.ModTime()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				err = seg.dropPartial(int64(len(line)), repair)
+			} else {
+				err = nil
+			}
+			return seg, err
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var evt munch.Event
+		err = json.Unmarshal(line, &evt)
+		if err != nil {
+			log.Printf("skipping invalid event %d in %s: %s", seg.next, path, err)
+			seg.next++
+			seg.size += int64(len(line))
+			continue
+		}
+		seg.add(evt, int64(len(line)))
+	}
+}
+
+func (seg *segment) dropPartial(length int64, repair bool) error {
+	if !repair {
+		log.Printf("skipping incomplete event at the end of %s", seg.path)
+		seg.size += length
+		return nil
+	}
+	log.Printf("cutting incomplete event off the end of %s", seg.path)
+	return os.Truncate(seg.path, seg.size)
+}
+
+// add indexes an event written at the end of the segment.
+func (seg *segment) add(evt munch.Event, length int64) {
+	seg.entries = append(seg.entries, entry{
+		seq:    seg.next,
+		offset: seg.size,
+		length: int(length),
+		at:     evt.At,
+		source: evt.Source,
+	})
+	seg.next++
+	seg.size += length
+	seg.sources[evt.Source]++
+	if len(seg.entries) == 1 || evt.At.Before(seg.minAt) {
+		seg.minAt = evt.At
+	}
+	if len(seg.entries) == 1 || evt.At.After(seg.maxAt) {
+		seg.maxAt = evt.At
+	}
+}
+
+// mayHold tells whether the segment can hold events matching the query.
+func (seg *segment) mayHold(q Query) bool {
+	switch {
+	case len(seg.entries) == 0:
+		return false
+	case q.After != 0 && seg.next <= q.After+1:
+		return false
+	case !q.Since.IsZero() && seg.maxAt.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !seg.minAt.Before(q.Until):
+		return false
+	}
+	if len(q.Sources) == 0 {
+		return true
+	}
+	for _, src := range q.Sources {
+		if seg.sources[src] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// read appends the events matching the query to recs, until there are limit
+// of them. A non-positive limit is no limit.
+func (seg *segment) read(q Query, recs []Record, limit int) ([]Record, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return recs, err
+	}
+	defer f.Close()
+
+	var buf []byte
+	for _, e := range seg.entries {
+		if limit > 0 && len(recs) >= limit {
+			break
+		}
+		if !q.matches(e) {
+			continue
+		}
+		if cap(buf) < e.length {
+			buf = make([]byte, e.length)
+		}
+		buf = buf[:e.length]
+		_, err := f.ReadAt(buf, e.offset)
+		if err != nil {
+			return recs, fmt.Errorf("cannot read event %d from %s: %s", e.seq, seg.path, err)
+		}
+		var evt munch.Event
+		err = json.Unmarshal(buf, &evt)
+		if err != nil {
+			return recs, fmt.Errorf("invalid event %d in %s: %s", e.seq, seg.path, err)
+		}
+		recs = append(recs, Record{Seq: e.seq, Event: evt})
+	}
+	return recs, nil
+}
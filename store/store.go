@@ -0,0 +1,315 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/szabba/munch"
+)
+
+// Options configure a Store. A new segment gets started once the current one
+// grows to SegmentSize bytes. Older segments are deleted when the store grows
+// over MaxSize bytes, or when they were last written to more than MaxAge ago.
+// A zero MaxSize or MaxAge is no limit.
+type Options struct {
+	SegmentSize int64
+	MaxSize     int64
+	MaxAge      time.Duration
+	Clock       func() time.Time
+}
+
+func DefaultOptions() Options {
+	return Options{
+		SegmentSize: 16 << 20,
+		Clock:       time.Now,
+	}
+}
+
+func (opts Options) Validate() error {
+	switch {
+	case opts.SegmentSize <= 0:
+		return fmt.Errorf("segment size must be positive, got %d", opts.SegmentSize)
+	case opts.MaxSize < 0:
+		return fmt.Errorf("max size cannot be negative, got %d", opts.MaxSize)
+	case opts.MaxAge < 0:
+		return fmt.Errorf("max age cannot be negative, got %s", opts.MaxAge)
+	}
+	return nil
+}
+
+// A Record is an event together with its sequence number in the store.
+type Record struct {
+	Seq   uint64
+	Event munch.Event
+}
+
+// A Query selects events from a store. No Sources and zero times match all
+// events. Since is inclusive and Until exclusive. Only events with sequence
+// numbers greater than After match.
+type Query struct {
+	Sources []string
+	Since   time.Time
+	Until   time.Time
+	After   uint64
+}
+
+func (q Query) matches(e entry) bool {
+	if e.seq <= q.After {
+		return false
+	}
+	if !q.Since.IsZero() && e.at.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.at.Before(q.Until) {
+		return false
+	}
+	if len(q.Sources) == 0 {
+		return true
+	}
+	for _, src := range q.Sources {
+		if e.source == src {
+			return true
+		}
+	}
+	return false
+}
+
+// A Store appends events to segment files in a directory. It keeps an index
+// of the events by time and source in memory and rebuilds it from the files
+// when opened, so there is no index file to get out of sync after a crash.
+//
+// Events are written to the operating system as they come, but only flushed
+// to disk by Sync and Close.
+type Store struct {
+	lock     sync.Mutex
+	dir      string
+	opts     Options
+	segments []*segment
+	active   *os.File
+	next     uint64
+}
+
+// Open loads the segments in dir, creating the directory when missing.
+func Open(dir string, opts Options) (*Store, error) {
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var bases []uint64
+	for _, info := range infos {
+		base, ok := parseSegmentName(info.Name())
+		if ok && info.Mode().IsRegular() {
+			bases = append(bases, base)
+		}
+	}
+	sort.Slice(bases, func(i, j int) bool { return bases[i] < bases[j] })
+
+	s := &Store{dir: dir, opts: opts, next: 1}
+	for i, base := range bases {
+		last := i == len(bases)-1
+		seg, err := loadSegment(segmentPath(dir, base), base, last)
+		if err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, seg)
+		if seg.next > s.next {
+			s.next = seg.next
+		}
+	}
+
+	if n := len(s.segments); n > 0 && s.segments[n-1].size < opts.SegmentSize {
+		s.active, err = os.OpenFile(s.segments[n-1].path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Append writes the event at the end of the store.
+func (s *Store) Append(evt munch.Event) error {
+	line, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.active == nil {
+		err = s.startSegment()
+		if err != nil {
+			return err
+		}
+	}
+	seg := s.segments[len(s.segments)-1]
+	n, err := s.active.Write(line)
+	if err != nil {
+		if n > 0 {
+			// Do not leave a partial event in the middle of the segment.
+			s.active.Truncate(seg.size)
+		}
+		return err
+	}
+
+	seg.add(evt, int64(n))
+	seg.modTime = s.opts.Clock()
+	s.next = seg.next
+	if seg.size >= s.opts.SegmentSize {
+		err = s.closeActive()
+		if err != nil {
+			return err
+		}
+		return s.prune(s.opts.Clock())
+	}
+	return nil
+}
+
+func (s *Store) startSegment() error {
+	path := segmentPath(s.dir, s.next)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	seg := newSegment(path, s.next)
+	seg.modTime = s.opts.Clock()
+	s.active = f
+	s.segments = append(s.segments, seg)
+	return nil
+}
+
+func (s *Store) closeActive() error {
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Sync()
+	closeErr := s.active.Close()
+	s.active = nil
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Read returns the events matching the query, in the order they were
+// appended. There are at most limit of them, unless the limit is not
+// positive.
+func (s *Store) Read(q Query, limit int) ([]Record, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var (
+		recs []Record
+		err  error
+	)
+	for _, seg := range s.segments {
+		if limit > 0 && len(recs) >= limit {
+			break
+		}
+		if !seg.mayHold(q) {
+			continue
+		}
+		recs, err = seg.read(q, recs, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return recs, nil
+}
+
+// Prune deletes the segments that are past the retention limits. The segment
+// being written to only gets deleted for being too old.
+func (s *Store) Prune() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.prune(s.opts.Clock())
+}
+
+func (s *Store) prune(now time.Time) error {
+	if s.opts.MaxAge > 0 {
+		cutoff := now.Add(-s.opts.MaxAge)
+		for len(s.segments) > 0 && s.segments[0].modTime.Before(cutoff) {
+			if len(s.segments) == 1 {
+				if len(s.segments[0].entries) == 0 {
+					break
+				}
+				// Keep an empty segment, so that sequence numbers do not
+				// start over after a restart.
+				err := s.closeActive()
+				if err == nil {
+					err = s.startSegment()
+				}
+				if err != nil {
+					return err
+				}
+			}
+			err := s.dropOldest()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.opts.MaxSize > 0 {
+		for len(s.segments) > 1 && s.size() > s.opts.MaxSize {
+			err := s.dropOldest()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) dropOldest() error {
+	err := os.Remove(s.segments[0].path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.segments[0] = nil
+	s.segments = s.segments[1:]
+	return nil
+}
+
+func (s *Store) size() int64 {
+	var size int64
+	for _, seg := range s.segments {
+		size += seg.size
+	}
+	return size
+}
+
+// Sync flushes the events appended so far to disk.
+func (s *Store) Sync() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.active == nil {
+		return nil
+	}
+	return s.active.Sync()
+}
+
+func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closeActive()
+}
@@ -0,0 +1,262 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/store"
+)
+
+var Start = time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestStoreReadsAppendedEventsInOrder(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s := openStore(t, dir, store.DefaultOptions())
+	defer s.Close()
+
+	// when
+	appendEvents(t, s, "app", "first", "second", "third")
+
+	// then
+	recs, err := s.Read(store.Query{}, 0)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assertMessages(t, recs, "first", "second", "third")
+}
+
+func TestStoreReadsEventsBySourceAndTime(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	opts := store.DefaultOptions()
+	opts.SegmentSize = 200
+	s := openStore(t, dir, opts)
+	defer s.Close()
+
+	appendEvents(t, s, "app", "0", "1", "2", "3")
+	appendEvents(t, s, "db", "0", "1", "2", "3")
+
+	// when
+	recs, err := s.Read(store.Query{
+		Sources: []string{"db"},
+		Since:   Start.Add(time.Minute),
+		Until:   Start.Add(3 * time.Minute),
+	}, 0)
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assertMessages(t, recs, "1", "2")
+	for _, rec := range recs {
+		assert.That(rec.Event.Source == "db", t.Errorf, "got event from source %q, want %q", rec.Event.Source, "db")
+	}
+	assert.That(len(segments(t, dir)) > 1, t.Errorf, "events were not split into segments")
+}
+
+func TestStoreContinuesReadingAfterCursor(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	opts := store.DefaultOptions()
+	opts.SegmentSize = 200
+	s := openStore(t, dir, opts)
+	defer s.Close()
+
+	appendEvents(t, s, "app", "0", "1", "2", "3", "4")
+
+	// when
+	first, err := s.Read(store.Query{}, 2)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	second, err := s.Read(store.Query{After: first[len(first)-1].Seq}, 2)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// then
+	assertMessages(t, first, "0", "1")
+	assertMessages(t, second, "2", "3")
+}
+
+func TestStoreKeepsEventsAcrossRestarts(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s := openStore(t, dir, store.DefaultOptions())
+	appendEvents(t, s, "app", "first", "second")
+	err := s.Close()
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	reopened := openStore(t, dir, store.DefaultOptions())
+	defer reopened.Close()
+	appendEvents(t, reopened, "app", "third")
+
+	// then
+	recs, err := reopened.Read(store.Query{}, 0)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assertMessages(t, recs, "first", "second", "third")
+	assert.That(recs[2].Seq > recs[1].Seq, t.Errorf, "sequence numbers started over: %d after %d", recs[2].Seq, recs[1].Seq)
+}
+
+func TestStoreRecoversFromCrashMidWrite(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s := openStore(t, dir, store.DefaultOptions())
+	appendEvents(t, s, "app", "first", "second")
+	s.Close()
+
+	segs := segments(t, dir)
+	f, err := os.OpenFile(segs[len(segs)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	f.WriteString(`{"source":"app","mess`)
+	f.Close()
+
+	// when
+	reopened := openStore(t, dir, store.DefaultOptions())
+	defer reopened.Close()
+	appendEvents(t, reopened, "app", "third")
+
+	// then
+	recs, err := reopened.Read(store.Query{}, 0)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assertMessages(t, recs, "first", "second", "third")
+}
+
+func TestStoreDropsOldestSegmentsOverMaxSize(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	opts := store.DefaultOptions()
+	opts.SegmentSize = 100
+	opts.MaxSize = 300
+	s := openStore(t, dir, opts)
+	defer s.Close()
+
+	// when
+	appendEvents(t, s, "app", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9")
+
+	// then
+	recs, err := s.Read(store.Query{}, 0)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(len(recs) > 0 && len(recs) < 10, t.Fatalf, "got %d events, want some but not all", len(recs))
+	assert.That(recs[len(recs)-1].Event.Message == "9", t.Errorf, "got last event %q, want %q", recs[len(recs)-1].Event.Message, "9")
+	assert.That(dirSize(t, dir) <= opts.MaxSize, t.Errorf, "store takes %d bytes, over the limit of %d", dirSize(t, dir), opts.MaxSize)
+}
+
+func TestStoreDropsSegmentsOverMaxAge(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	now := Start
+	opts := store.DefaultOptions()
+	opts.SegmentSize = 100
+	opts.MaxAge = time.Hour
+	opts.Clock = func() time.Time { return now }
+	s := openStore(t, dir, opts)
+	defer s.Close()
+
+	// Each segment fits two events.
+	appendEvents(t, s, "app", "0", "1", "2", "3")
+	now = now.Add(2 * time.Hour)
+	appendEvents(t, s, "app", "4", "5")
+
+	// when
+	err := s.Prune()
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	recs, err := s.Read(store.Query{}, 0)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assertMessages(t, recs, "4", "5")
+}
+
+func TestStoreKeepsSequenceAfterDroppingAllSegments(t *testing.T) {
+	// given
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	now := Start
+	opts := store.DefaultOptions()
+	opts.MaxAge = time.Hour
+	opts.Clock = func() time.Time { return now }
+	s := openStore(t, dir, opts)
+	appendEvents(t, s, "app", "0", "1")
+	now = now.Add(2 * time.Hour)
+	err := s.Prune()
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	s.Close()
+
+	// when
+	reopened := openStore(t, dir, opts)
+	defer reopened.Close()
+	appendEvents(t, reopened, "app", "2")
+
+	// then
+	recs, err := reopened.Read(store.Query{}, 0)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assertMessages(t, recs, "2")
+	assert.That(recs[0].Seq == 3, t.Errorf, "got sequence number %d, want %d", recs[0].Seq, 3)
+}
+
+func openStore(t *testing.T, dir string, opts store.Options) *store.Store {
+	t.Helper()
+	s, err := store.Open(dir, opts)
+	assert.That(err == nil, t.Fatalf, "cannot open store: %s", err)
+	return s
+}
+
+// appendEvents adds events with the messages, a minute apart from Start.
+func appendEvents(t *testing.T, s *store.Store, source string, msgs ...string) {
+	t.Helper()
+	for i, msg := range msgs {
+		evt := munch.Event{Source: source, At: Start.Add(time.Duration(i) * time.Minute), Message: msg}
+		err := s.Append(evt)
+		assert.That(err == nil, t.Fatalf, "cannot append event: %s", err)
+	}
+}
+
+func assertMessages(t *testing.T, recs []store.Record, want ...string) {
+	t.Helper()
+
+	got := make([]string, len(recs))
+	for i, rec := range recs {
+		got[i] = rec.Event.Message
+	}
+	assert.That(len(got) == len(want), t.Fatalf, "got messages %q, want %q", got, want)
+	for i := range want {
+		assert.That(got[i] == want[i], t.Errorf, "got messages %q, want %q", got, want)
+	}
+}
+
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+store.SegmentExt))
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	return paths
+}
+
+func dirSize(t *testing.T, dir string) int64 {
+	t.Helper()
+	var size int64
+	for _, path := range segments(t, dir) {
+		info, err := os.Stat(path)
+		assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+		size += info.Size()
+	}
+	return size
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "munch-store")
+	assert.That(err == nil, t.Fatalf, "cannot create temporary directory: %s", err)
+	return dir
+}
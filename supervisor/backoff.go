@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package supervisor
+
+import (
+	"fmt"
+	"time"
+)
+
+// A Backoff says how long to wait before restarting a failed source. The
+// first restart happens after Initial, and each next one waits twice as long
+// as the one before, up to Max.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial: time.Second,
+		Max:     time.Minute,
+	}
+}
+
+func (b Backoff) Validate() error {
+	switch {
+	case b.Initial <= 0:
+		return fmt.Errorf("initial restart delay must be positive, got %s", b.Initial)
+	case b.Max < b.Initial:
+		return fmt.Errorf("max restart delay %s is shorter than the initial one, %s", b.Max, b.Initial)
+	}
+	return nil
+}
+
+// Delay returns how long to wait before the restart following the given
+// number of failed ones.
+func (b Backoff) Delay(failed int) time.Duration {
+	delay := b.Initial
+	for i := 0; i < failed && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}
@@ -0,0 +1,38 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package supervisor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/supervisor"
+)
+
+func TestBackoffDoublesDelayUpToMax(t *testing.T) {
+	backoff := supervisor.Backoff{Initial: time.Second, Max: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+
+	for failed, delayWant := range want {
+		// when
+		delay := backoff.Delay(failed)
+
+		// then
+		assert.That(delay == delayWant, t.Errorf, "after %d failures got delay %s, want %s", failed, delay, delayWant)
+	}
+}
+
+func TestBackoffRejectsMaxShorterThanInitial(t *testing.T) {
+	// given
+	backoff := supervisor.Backoff{Initial: time.Minute, Max: time.Second}
+
+	// when
+	err := backoff.Validate()
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+}
@@ -0,0 +1,194 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package supervisor
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/inputs"
+	"github.com/szabba/munch/sources"
+)
+
+type BroadcastService interface {
+	Broadcast(msg interface{})
+}
+
+// A Supervisor runs a source, restarting it whenever it fails. Each failure
+// and change of the source's status gets broadcast. A source stopped while it
+// waits to be restarted gets its last error broadcast again, as one after which
+// it is not alive. A source that read all of its input has not failed, so it
+// only gets the ended status.
+//
+// The restarts back off exponentially. A source that ran for longer than the
+// maximum delay before failing is restarted after the initial delay again.
+type Supervisor struct {
+	once      sync.Once
+	name      string
+	newSource func() (*sources.Source, error)
+	backoff   Backoff
+	cast      BroadcastService
+	stopped   chan struct{}
+
+	lock   sync.Mutex
+	src    *sources.Source
+	status munch.SourceStatus
+}
+
+// New creates a supervisor for the source named name, created by newSource.
+// The first source gets created right away, and its error returned.
+func New(name string, newSource func() (*sources.Source, error), backoff Backoff, cast BroadcastService) (*Supervisor, error) {
+	src, err := newSource()
+	if err != nil {
+		return nil, err
+	}
+	return &Supervisor{
+		name:      name,
+		newSource: newSource,
+		backoff:   backoff,
+		cast:      cast,
+		stopped:   make(chan struct{}),
+		src:       src,
+		status: munch.SourceStatus{
+			Source: name,
+			State:  munch.SourceRunning,
+			Since:  time.Now(),
+		},
+	}, nil
+}
+
+func (s *Supervisor) Status() munch.SourceStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.status
+}
+
+// Run processes the source until the supervisor is stopped. It only returns
+// then, whatever happens to the source.
+func (s *Supervisor) Run() error {
+	s.lock.Lock()
+	src := s.src
+	s.lock.Unlock()
+
+	failed := 0
+	for {
+		started := time.Now()
+		err := src.Process()
+		if s.isStopped() {
+			return nil
+		}
+		if err == nil {
+			log.Printf("source %q reached the end of its input", s.name)
+			s.setStatus(munch.SourceEnded, nil, nil)
+			<-s.stopped
+			return nil
+		}
+
+		if time.Since(started) > s.backoff.Max {
+			failed = 0
+		}
+		for {
+			delay := s.backoff.Delay(failed)
+			failed++
+			s.fail(err, delay)
+
+			select {
+			case <-s.stopped:
+				log.Printf("source %q stopped before being restarted", s.name)
+				s.report(err, false)
+				return nil
+			case <-time.After(delay):
+			}
+			src, err = s.restart()
+			if err == nil {
+				break
+			}
+		}
+		s.setStatus(munch.SourceRunning, nil, nil)
+	}
+}
+
+func (s *Supervisor) fail(err error, delay time.Duration) {
+	log.Printf("source %q failed, restarting in %s: %s", s.name, delay, err)
+	at := s.report(err, true)
+	retryAt := at.Add(delay)
+	s.setStatus(munch.SourceFailed, err, &retryAt)
+}
+
+// report broadcasts the error. The source is alive when it is going to be
+// restarted.
+func (s *Supervisor) report(err error, alive bool) time.Time {
+	now := time.Now()
+	s.cast.Broadcast(munch.ErrorEvent{
+		Source:  s.name,
+		Kind:    errorKind(err),
+		Message: err.Error(),
+		At:      now,
+		Alive:   alive,
+	})
+	return now
+}
+
+func (s *Supervisor) restart() (*sources.Source, error) {
+	src, err := s.newSource()
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.isStopped() {
+		src.Stop()
+	}
+	s.src = src
+	s.status.Restarts++
+	return src, nil
+}
+
+func (s *Supervisor) setStatus(state string, err error, retryAt *time.Time) {
+	s.lock.Lock()
+	s.status.State = state
+	s.status.Since = time.Now()
+	s.status.Error = ""
+	if err != nil {
+		s.status.Error = err.Error()
+	}
+	s.status.RetryAt = retryAt
+	status := s.status
+	s.lock.Unlock()
+
+	s.cast.Broadcast(status)
+}
+
+func (s *Supervisor) Stop() {
+	s.once.Do(func() {
+		close(s.stopped)
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.src.Stop()
+	})
+}
+
+func (s *Supervisor) isStopped() bool {
+	select {
+	case <-s.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+func errorKind(err error) string {
+	switch err.(type) {
+	case *sources.ParserError:
+		return munch.ErrorKindParser
+	case *inputs.ExitError:
+		return munch.ErrorKindExit
+	default:
+		return munch.ErrorKindInput
+	}
+}
@@ -0,0 +1,273 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package supervisor_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch"
+	"github.com/szabba/munch/inputs"
+	"github.com/szabba/munch/sources"
+	"github.com/szabba/munch/supervisor"
+)
+
+const Timeout = 5 * time.Second
+
+var Backoff = supervisor.Backoff{Initial: 10 * time.Millisecond, Max: 40 * time.Millisecond}
+
+func TestSupervisorRestartsFailedSource(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	newSource := SourceSequence(failingSource(errors.New("cannot read")), blockingSource)
+	sup, err := supervisor.New("app", newSource, Backoff, cast)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	done := runInBackground(sup)
+	defer func() { sup.Stop(); <-done }()
+
+	// then
+	errEvt := cast.Next(t).(munch.ErrorEvent)
+	assert.That(errEvt.Source == "app", t.Errorf, "got error event for source %q, want %q", errEvt.Source, "app")
+	assert.That(errEvt.Message == "cannot read", t.Errorf, "got error message %q, want %q", errEvt.Message, "cannot read")
+	assert.That(errEvt.Kind == munch.ErrorKindInput, t.Errorf, "got error kind %q, want %q", errEvt.Kind, munch.ErrorKindInput)
+	assert.That(errEvt.Alive, t.Errorf, "error event says the source is dead")
+
+	failed := cast.Next(t).(munch.SourceStatus)
+	assert.That(failed.State == munch.SourceFailed, t.Errorf, "got state %q, want %q", failed.State, munch.SourceFailed)
+	assert.That(failed.RetryAt != nil, t.Errorf, "failed status has no retry time")
+
+	running := cast.Next(t).(munch.SourceStatus)
+	assert.That(running.State == munch.SourceRunning, t.Errorf, "got state %q, want %q", running.State, munch.SourceRunning)
+	assert.That(running.Restarts == 1, t.Errorf, "got %d restarts, want %d", running.Restarts, 1)
+	assert.That(sup.Status() == running, t.Errorf, "got status %#v, want %#v", sup.Status(), running)
+}
+
+func TestSupervisorBacksOffWhenSourceKeepsFailing(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	newSource := func() (*sources.Source, error) { return failingSource(errors.New("cannot read"))() }
+	sup, err := supervisor.New("app", newSource, Backoff, cast)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	done := runInBackground(sup)
+	defer func() { sup.Stop(); <-done }()
+
+	// then
+	var delays []time.Duration
+	for len(delays) < 4 {
+		status, ok := cast.Next(t).(munch.SourceStatus)
+		if ok && status.State == munch.SourceFailed {
+			delays = append(delays, status.RetryAt.Sub(status.Since).Round(10*time.Millisecond))
+		}
+	}
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond}
+	for i := range want {
+		assert.That(delays[i] == want[i], t.Errorf, "got delays %s, want %s", delays, want)
+	}
+}
+
+func TestSupervisorContainsExitOfCommand(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	newSource := SourceSequence(exitingSource, blockingSource)
+	sup, err := supervisor.New("app", newSource, Backoff, cast)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	done := runInBackground(sup)
+	defer func() { sup.Stop(); <-done }()
+
+	// then
+	errEvt := cast.Next(t).(munch.ErrorEvent)
+	assert.That(errEvt.Kind == munch.ErrorKindExit, t.Errorf, "got error kind %q, want %q", errEvt.Kind, munch.ErrorKindExit)
+	cast.Next(t)
+	running := cast.Next(t).(munch.SourceStatus)
+	assert.That(running.State == munch.SourceRunning, t.Errorf, "got state %q, want %q", running.State, munch.SourceRunning)
+	select {
+	case err := <-done:
+		t.Fatalf("supervisor returned %v before being stopped", err)
+	default:
+	}
+}
+
+func TestSupervisorDoesNotRestartSourceThatEnded(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	newSource := SourceSequence(endingSource, blockingSource)
+	sup, err := supervisor.New("app", newSource, Backoff, cast)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	// when
+	done := runInBackground(sup)
+	defer func() { sup.Stop(); <-done }()
+
+	// then
+	msg := cast.Next(t)
+	status, ok := msg.(munch.SourceStatus)
+	assert.That(ok, t.Fatalf, "got %#v broadcast first, want the ended status", msg)
+	assert.That(status.State == munch.SourceEnded, t.Errorf, "got state %q, want %q", status.State, munch.SourceEnded)
+	cast.AssertNothing(t, 5*Backoff.Max)
+}
+
+func TestSupervisorStopsWhileWaitingToRestart(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	backoff := supervisor.Backoff{Initial: time.Hour, Max: time.Hour}
+	newSource := SourceSequence(failingSource(errors.New("cannot read")))
+	sup, err := supervisor.New("app", newSource, backoff, cast)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	done := runInBackground(sup)
+	cast.Next(t)
+
+	// when
+	sup.Stop()
+
+	// then
+	select {
+	case err := <-done:
+		assert.That(err == nil, t.Errorf, "unexpected error: %s", err)
+	case <-time.After(Timeout):
+		t.Fatalf("supervisor did not stop")
+	}
+}
+
+func TestSupervisorReportsSourceStoppedWhileWaitingToRestartAsDead(t *testing.T) {
+	// given
+	cast := NewCaptureBroadcast()
+	backoff := supervisor.Backoff{Initial: time.Hour, Max: time.Hour}
+	newSource := SourceSequence(failingSource(errors.New("cannot read")))
+	sup, err := supervisor.New("app", newSource, backoff, cast)
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+
+	done := runInBackground(sup)
+	cast.Next(t)
+	cast.Next(t)
+
+	// when
+	sup.Stop()
+	<-done
+
+	// then
+	errEvt := cast.Next(t).(munch.ErrorEvent)
+	assert.That(!errEvt.Alive, t.Errorf, "error event says the stopped source is alive")
+	assert.That(errEvt.Message == "cannot read", t.Errorf, "got error message %q, want %q", errEvt.Message, "cannot read")
+}
+
+func TestSupervisorFailsWhenFirstSourceCannotBeCreated(t *testing.T) {
+	// given
+	errWant := errors.New("no such file")
+	newSource := func() (*sources.Source, error) { return nil, errWant }
+
+	// when
+	sup, err := supervisor.New("app", newSource, Backoff, NewCaptureBroadcast())
+
+	// then
+	assert.That(err == errWant, t.Errorf, "got error %v, want %q", err, errWant)
+	assert.That(sup == nil, t.Errorf, "got supervisor %#v, want none", sup)
+}
+
+func runInBackground(sup *supervisor.Supervisor) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- sup.Run() }()
+	return done
+}
+
+// SourceSequence creates the sources with each function in turn, and keeps
+// using the last one.
+func SourceSequence(newSources ...func() (*sources.Source, error)) func() (*sources.Source, error) {
+	var (
+		lock sync.Mutex
+		next int
+	)
+	return func() (*sources.Source, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		newSource := newSources[next]
+		if next < len(newSources)-1 {
+			next++
+		}
+		return newSource()
+	}
+}
+
+func failingSource(err error) func() (*sources.Source, error) {
+	return func() (*sources.Source, error) {
+		in, inWriter := io.Pipe()
+		inWriter.CloseWithError(err)
+		return sources.New(in, DiscardParser{}), nil
+	}
+}
+
+func exitingSource() (*sources.Source, error) {
+	input, err := inputs.NewExec("app", exec.Command("true"))
+	if err != nil {
+		return nil, err
+	}
+	newParser := func(string) (io.WriteCloser, error) { return DiscardParser{}, nil }
+	return sources.NewFanout(input, newParser), nil
+}
+
+func endingSource() (*sources.Source, error) {
+	in := ioutil.NopCloser(strings.NewReader("all of it"))
+	return sources.New(in, DiscardParser{}), nil
+}
+
+func blockingSource() (*sources.Source, error) {
+	in, _ := io.Pipe()
+	return sources.New(in, DiscardParser{}), nil
+}
+
+type DiscardParser struct{}
+
+func (DiscardParser) Write(p []byte) (int, error) { return len(p), nil }
+
+func (DiscardParser) Close() error { return nil }
+
+type CaptureBroadcast struct {
+	msgs chan interface{}
+}
+
+func NewCaptureBroadcast() *CaptureBroadcast {
+	return &CaptureBroadcast{msgs: make(chan interface{}, 64)}
+}
+
+func (c *CaptureBroadcast) Broadcast(msg interface{}) {
+	select {
+	case c.msgs <- msg:
+	default:
+	}
+}
+
+func (c *CaptureBroadcast) Next(t *testing.T) interface{} {
+	t.Helper()
+	select {
+	case msg := <-c.msgs:
+		return msg
+	case <-time.After(Timeout):
+		t.Fatalf("nothing was broadcast")
+		return nil
+	}
+}
+
+func (c *CaptureBroadcast) AssertNothing(t *testing.T, wait time.Duration) {
+	t.Helper()
+	select {
+	case msg := <-c.msgs:
+		t.Errorf("unexpected broadcast: %#v", msg)
+	case <-time.After(wait):
+	}
+}
@@ -0,0 +1,142 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package tagjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A TypeTag names a kind of message on the wire. The version changes whenever
+// the message's shape does in a way older clients would not understand.
+type TypeTag struct {
+	Name    string
+	Version int
+}
+
+// String gives the tag as it appears on the wire: the name and the version
+// separated by "/v", like "event/v1".
+func (tag TypeTag) String() string {
+	return tag.Name + "/v" + strconv.Itoa(tag.Version)
+}
+
+func ParseTypeTag(s string) (TypeTag, error) {
+	i := strings.LastIndex(s, "/v")
+	if i <= 0 {
+		return TypeTag{}, fmt.Errorf("tag %q has no version", s)
+	}
+	version, err := strconv.Atoi(s[i+2:])
+	if err != nil || version <= 0 {
+		return TypeTag{}, fmt.Errorf("tag %q has an invalid version", s)
+	}
+	return TypeTag{Name: s[:i], Version: version}, nil
+}
+
+// A Registry knows the tags of the message types that can go on the wire, so
+// that renaming a Go type does not change the protocol.
+type Registry struct {
+	tags  map[reflect.Type]TypeTag
+	types map[TypeTag]reflect.Type
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		tags:  make(map[reflect.Type]TypeTag),
+		types: make(map[TypeTag]reflect.Type),
+	}
+}
+
+// Register makes messages of the same type as v go on the wire with the tag.
+// It panics when either the type or the tag is already registered.
+func (reg *Registry) Register(tag TypeTag, v interface{}) {
+	typ := reflect.TypeOf(v)
+	if other, ok := reg.tags[typ]; ok {
+		panic(fmt.Sprintf("tagjson: type %s is already registered as %s", typ, other))
+	}
+	if other, ok := reg.types[tag]; ok {
+		panic(fmt.Sprintf("tagjson: tag %s is already registered for type %s", tag, other))
+	}
+	reg.tags[typ] = tag
+	reg.types[tag] = typ
+}
+
+// TagOf returns the tag registered for the type.
+func (reg *Registry) TagOf(typ reflect.Type) (TypeTag, bool) {
+	tag, ok := reg.tags[typ]
+	return tag, ok
+}
+
+// Versions lists the versions registered with the tag name.
+func (reg *Registry) Versions(name string) []int {
+	var versions []int
+	for tag := range reg.types {
+		if tag.Name == name {
+			versions = append(versions, tag.Version)
+		}
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// Marshal encodes the message tagged with its registered tag.
+func (reg *Registry) Marshal(msg interface{}) ([]byte, error) {
+	tag, ok := reg.TagOf(reflect.TypeOf(msg))
+	if !ok {
+		return nil, fmt.Errorf("message type %T has no registered tag", msg)
+	}
+	return Tag(tag.String(), msg)
+}
+
+// FormatMessage writes the message out like Marshal, followed by a newline.
+func (reg *Registry) FormatMessage(w io.Writer, msg interface{}) error {
+	out, err := reg.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// Unmarshal decodes a tagged message into a new value of the type registered
+// for its tag.
+func (reg *Registry) Unmarshal(raw json.RawMessage) (interface{}, error) {
+	rawTag, inner, err := Untag(raw)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := ParseTypeTag(rawTag)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := reg.Lookup(tag)
+	if err != nil {
+		return nil, err
+	}
+	ptr := reflect.New(typ)
+	err = json.Unmarshal(inner, ptr.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s message: %s", tag, err)
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// Lookup returns the type registered for the tag. When there is none, the
+// error tells apart unknown tags from unsupported versions of known ones.
+func (reg *Registry) Lookup(tag TypeTag) (reflect.Type, error) {
+	typ, ok := reg.types[tag]
+	if ok {
+		return typ, nil
+	}
+	versions := reg.Versions(tag.Name)
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("unknown message tag %s", tag)
+	}
+	return nil, fmt.Errorf("unsupported version %d of %s messages (supported versions: %v)", tag.Version, tag.Name, versions)
+}
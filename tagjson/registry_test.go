@@ -0,0 +1,123 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package tagjson_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/szabba/assert"
+
+	"github.com/szabba/munch/tagjson"
+)
+
+type Ping struct {
+	N int `json:"n"`
+}
+
+var PingTag = tagjson.TypeTag{Name: "ping", Version: 2}
+
+func TestRegistryMarshalsWithRegisteredTag(t *testing.T) {
+	// given
+	reg := tagjson.NewRegistry()
+	reg.Register(PingTag, Ping{})
+
+	// when
+	out, err := reg.Marshal(Ping{N: 1})
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(string(out) == `{"ping/v2":{"n":1}}`, t.Errorf, "got encoding %s, want %s", out, `{"ping/v2":{"n":1}}`)
+}
+
+func TestRegistryFailsToMarshalUnregisteredType(t *testing.T) {
+	// given
+	reg := tagjson.NewRegistry()
+
+	// when
+	_, err := reg.Marshal(Ping{})
+
+	// then
+	assert.That(err != nil, t.Errorf, "got no error, wanted one")
+}
+
+func TestRegistryFormatsMessageOnALine(t *testing.T) {
+	// given
+	reg := tagjson.NewRegistry()
+	reg.Register(PingTag, Ping{})
+	var buf bytes.Buffer
+
+	// when
+	err := reg.FormatMessage(&buf, Ping{N: 1})
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(buf.String() == "{\"ping/v2\":{\"n\":1}}\n", t.Errorf, "got output %q", buf.String())
+}
+
+func TestRegistryUnmarshalsRegisteredType(t *testing.T) {
+	// given
+	reg := tagjson.NewRegistry()
+	reg.Register(PingTag, Ping{})
+
+	// when
+	msg, err := reg.Unmarshal([]byte(`{"ping/v2": {"n": 3}}`))
+
+	// then
+	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)
+	assert.That(msg == Ping{N: 3}, t.Errorf, "got message %#v, want %#v", msg, Ping{N: 3})
+}
+
+func TestRegistryRejectsUnsupportedVersion(t *testing.T) {
+	// given
+	reg := tagjson.NewRegistry()
+	reg.Register(PingTag, Ping{})
+
+	// when
+	_, err := reg.Unmarshal([]byte(`{"ping/v1": {"n": 3}}`))
+
+	// then
+	assert.That(err != nil, t.Fatalf, "got no error, wanted one")
+	assert.That(strings.Contains(err.Error(), "unsupported version"), t.Errorf, "got error %q, want one about the version", err)
+}
+
+func TestRegistryPanicsOnDuplicateTag(t *testing.T) {
+	// given
+	reg := tagjson.NewRegistry()
+	reg.Register(PingTag, Ping{})
+
+	defer func() {
+		// then
+		assert.That(recover() != nil, t.Errorf, "registering a tag twice did not panic")
+	}()
+
+	// when
+	reg.Register(PingTag, "")
+}
+
+func TestParseTypeTag(t *testing.T) {
+	cases := []struct {
+		in    string
+		want  tagjson.TypeTag
+		valid bool
+	}{
+		{"event/v1", tagjson.TypeTag{Name: "event", Version: 1}, true},
+		{"source/status/v12", tagjson.TypeTag{Name: "source/status", Version: 12}, true},
+		{"event", tagjson.TypeTag{}, false},
+		{"event/v0", tagjson.TypeTag{}, false},
+		{"event/vx", tagjson.TypeTag{}, false},
+		{"/v1", tagjson.TypeTag{}, false},
+	}
+
+	for _, c := range cases {
+		// when
+		got, err := tagjson.ParseTypeTag(c.in)
+
+		// then
+		assert.That((err == nil) == c.valid, t.Errorf, "%q: got error %v, want valid %t", c.in, err, c.valid)
+		assert.That(got == c.want, t.Errorf, "%q: got tag %#v, want %#v", c.in, got, c.want)
+	}
+}
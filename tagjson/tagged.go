@@ -7,13 +7,12 @@ package tagjson
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 )
 
 var errNotTagged = errors.New("not proper tagged message")
 
-func TagWithType(v interface{}) ([]byte, error) {
-	tag := fmt.Sprintf("%T", v)
+// Tag encodes v as a JSON object with the tag as its only key.
+func Tag(tag string, v interface{}) ([]byte, error) {
 	tagged := map[string]interface{}{tag: v}
 	return json.Marshal(tagged)
 }
@@ -12,13 +12,13 @@ import (
 	"github.com/szabba/munch/tagjson"
 )
 
-func TestTagWithTypeOK(t *testing.T) {
+func TestTagOK(t *testing.T) {
 	// given
 	v := ""
-	outWant := []byte(`{"string":""}`)
+	outWant := []byte(`{"text":""}`)
 
 	// when
-	out, err := tagjson.TagWithType(v)
+	out, err := tagjson.Tag("text", v)
 
 	// then
 	assert.That(err == nil, t.Fatalf, "unexpected error: %s", err)